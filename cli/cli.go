@@ -1,13 +1,17 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"synrax/reporter"
 	"synrax/toolkit"
+	"syscall"
 
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
@@ -19,74 +23,383 @@ var rootCommand = &cobra.Command{
 }
 
 var readDocs = &cobra.Command{
-	Use:   "read [repo_id] [file_path] [oidc_token] [branch_name]",
-	Short: "Executes the unittest given `repo_id` and `file_path`",
-	Long:  "Reads a file from the provided path and performs operations on it. Repo id is required to query user's configuration for the app. File path expects API documentation to create a unittest report.",
-	Args:  cobra.ExactArgs(4),
+	Use:   "read --repo-id ID --docs PATH [--oidc-token TOKEN] [--branch NAME]",
+	Short: "Executes the unittest given `--repo-id` and `--docs`",
+	Long:  "Reads a file from the provided path and performs operations on it. Repo id is required to query user's configuration for the app. File path expects API documentation to create a unittest report. Every flag falls back to SYNRAX_<FLAG> and then to synrax.yaml; the positional form `read [repo_id] [file_path] [oidc_token] [branch_name]` still works but is deprecated.",
+	Args:  cobra.MaximumNArgs(4),
 	Run: func(cmd *cobra.Command, args []string) {
-		repoID := args[0]
-		filePath := args[1]
-		oidcToken := args[2]
-		targetBranch := args[3]
-		log.Printf("cli.read: starting repo_id=%s file_path=%s", repoID, filePath)
+		if err := resolveSettings(cmd, args, "repo-id", "docs", "oidc-token", "branch"); err != nil {
+			slog.Error("cli.read: invalid settings", "error", err)
+			exitWith(exitConfig, err)
+		}
+		runID := uuid.NewString()
+		if err := setupLogging(cmd, runID); err != nil {
+			exitWith(exitConfig, err)
+		}
+		flags := cmd.Flags()
+		repoID, _ := flags.GetString("repo-id")
+		filePath, _ := flags.GetString("docs")
+		oidcToken, _ := flags.GetString("oidc-token")
+		targetBranch, _ := flags.GetString("branch")
+		noStore, _ := flags.GetBool("no-store")
+		uploadCases, _ := flags.GetBool("upload-cases")
+		outbox, _ := flags.GetString("outbox")
+		noOIDC, _ := flags.GetBool("no-oidc")
+		fromStorage, _ := flags.GetBool("baseline-from-storage")
+		slog.Info("cli.read: starting", "repo_id", repoID, "file_path", filePath, "no_store", noStore, "no_oidc", noOIDC)
 
 		// ---- parameter validation ---
+		required := []string{"repo-id", "docs"}
+		if !noOIDC {
+			required = append(required, "oidc-token")
+		}
+		if !noStore || fromStorage {
+			required = append(required, "branch")
+		}
+		if err := requireFlags(cmd, required...); err != nil {
+			exitWith(exitConfig, err)
+		}
 
 		// 1) File Path validation
 		_, err := os.Stat(filePath)
 		if errors.Is(err, os.ErrNotExist) {
-			log.Printf("Given path does not exist: %s.", filePath)
-			os.Exit(1)
+			exitWith(exitConfig, fmt.Errorf("given path does not exist: %s", filePath))
 		}
 
-		// 2) Validate Token by Calling server
-		log.Printf("runner: config fetched repo_id=%s", repoID)
-		valid, err := toolkit.SynraxOIDCCaller(repoID, oidcToken)
+		opts, err := runOptions(cmd)
 		if err != nil {
-			log.Println(err.Error())
-			os.Exit(1)
+			slog.Error("cli.read: invalid options", "error", err)
+			exitWith(exitConfig, err)
 		}
-		if !valid {
-			log.Println("Passed invalid OIDC token.")
-			os.Exit(1)
+		opts.RunID = runID
+		gate, err := gateOptions(cmd)
+		if err != nil {
+			slog.Error("cli.read: invalid options", "error", err)
+			exitWith(exitConfig, err)
+		}
+		if opts.Baseline, err = loadBaseline(cmd, repoID, targetBranch); err != nil {
+			slog.Error("cli.read: baseline failed", "repo_id", repoID, "error", err)
+			if errors.Is(err, errStorage) {
+				exitWith(apiErrorCode(err, exitStorage), err)
+			}
+			exitWith(exitConfig, err)
+		}
+		if gate.failOn == failOnNew && opts.Baseline == nil {
+			exitWith(exitConfig, errors.New("--fail-on new needs --baseline or --baseline-from-storage"))
 		}
 
-		// 3) get config from DB
-		log.Printf("runner: start repo_id=%s file=%s", repoID, filePath)
-		config, err := toolkit.SynraxConfigCaller(repoID)
+		// 2) Validate Token by Calling server, 3) get config from DB
+		slog.Info("runner: start", "repo_id", repoID, "file", filePath)
+		config, err := loadRepoConfig(cmd.Context(), repoID, oidcToken, noOIDC)
 		if err != nil {
-			log.Printf("runner: config fetch failed repo_id=%s error=%v", repoID, err)
-			os.Exit(1)
+			slog.Error("cli.read: config failed", "repo_id", repoID, "error", err)
+			exitWith(apiErrorCode(err, exitConfig), err)
 		}
 		// 4) Checks passed. Run main function to gather unittest report
-		report, err := reporter.RunUnittest(filePath, config, repoID)
+		report, err := reporter.RunUnittest(cmd.Context(), filePath, config, repoID, opts)
+		if err != nil {
+			slog.Error("cli.read: failed", "repo_id", repoID, "error", err)
+			exitWith(runErrorCode(err), err)
+		}
+		publishGitHub(cmd, report)
+		if report.Interrupted {
+			// partial results are on disk; do not store metrics for an incomplete run
+			slog.Warn("cli.read: interrupted", "repo_id", repoID, "skipped", report.Summary.Skipped)
+			fmt.Fprintln(os.Stderr, "Run interrupted; partial report written.")
+			os.Exit(exitInterrupted)
+		}
+		slog.Info("cli.read: completed", "repo_id", repoID)
+
+		if noStore {
+			slog.Info("cli.read: storage disabled, metrics not submitted", "repo_id", repoID)
+		} else if err := storeRun(cmd.Context(), repoID, targetBranch, report, uploadCases, outbox); err != nil {
+			exitWith(apiErrorCode(err, exitStorage), err)
+		}
+		exitForReport("cli.read", repoID, targetBranch, report, gate)
+		slog.Info("cli.read: all processes completed")
+	},
+}
+
+var rerunFailed = &cobra.Command{
+	Use:   "rerun --repo-id ID [--oidc-token TOKEN] [--from PATH]",
+	Short: "Reruns the cases that did not pass in a previous report",
+	Long:  "Reuses the spec saved in a previous report.json instead of generating a new one, executes only the cases that did not pass and writes a merged report marking which failures are now fixed. Metrics are not submitted for reruns. Flags fall back to SYNRAX_<FLAG> and synrax.yaml like `read`.",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveSettings(cmd, args, "repo-id", "oidc-token"); err != nil {
+			slog.Error("cli.rerun: invalid settings", "error", err)
+			exitWith(exitConfig, err)
+		}
+		runID := uuid.NewString()
+		if err := setupLogging(cmd, runID); err != nil {
+			exitWith(exitConfig, err)
+		}
+		flags := cmd.Flags()
+		repoID, _ := flags.GetString("repo-id")
+		oidcToken, _ := flags.GetString("oidc-token")
+		noOIDC, _ := flags.GetBool("no-oidc")
+		from, _ := flags.GetString("from")
+		slog.Info("cli.rerun: starting", "repo_id", repoID, "no_oidc", noOIDC)
+
+		required := []string{"repo-id"}
+		if !noOIDC {
+			required = append(required, "oidc-token")
+		}
+		if err := requireFlags(cmd, required...); err != nil {
+			exitWith(exitConfig, err)
+		}
+
+		opts, err := runOptions(cmd)
+		if err != nil {
+			slog.Error("cli.rerun: invalid options", "error", err)
+			exitWith(exitConfig, err)
+		}
+		opts.RunID = runID
+		if from == "" {
+			if from, err = opts.Output.JSONPath(); err != nil {
+				exitWith(exitConfig, err)
+			}
+		}
+		if _, err := os.Stat(from); errors.Is(err, os.ErrNotExist) {
+			exitWith(exitConfig, fmt.Errorf("given report does not exist: %s", from))
+		}
+		gate, err := gateOptions(cmd)
+		if err != nil {
+			slog.Error("cli.rerun: invalid options", "error", err)
+			exitWith(exitConfig, err)
+		}
+		if opts.Baseline, err = loadBaseline(cmd, repoID, ""); err != nil {
+			slog.Error("cli.rerun: baseline failed", "repo_id", repoID, "error", err)
+			exitWith(exitConfig, err)
+		}
+		if gate.failOn == failOnNew && opts.Baseline == nil {
+			exitWith(exitConfig, errors.New("--fail-on new needs --baseline"))
+		}
+		config, err := loadRepoConfig(cmd.Context(), repoID, oidcToken, noOIDC)
 		if err != nil {
-			log.Printf("cli.read: failed repo_id=%s error=%v", repoID, err)
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			slog.Error("cli.rerun: config failed", "repo_id", repoID, "error", err)
+			exitWith(apiErrorCode(err, exitConfig), err)
 		}
-		log.Printf("cli.read: completed repo_id=%s", repoID)
 
-		if err := toolkit.SynraxReportStorage(repoID, targetBranch, report); err != nil {
-			log.Printf("cli.submission: failed repo_id=%s error=%v", repoID, err)
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		slog.Info("cli.rerun: rerunning", "from", from)
+		report, err := reporter.Rerun(cmd.Context(), from, config, opts)
+		if err != nil {
+			slog.Error("cli.rerun: failed", "repo_id", repoID, "error", err)
+			exitWith(runErrorCode(err), err)
+		}
+		slog.Info("cli.rerun: completed", "repo_id", repoID, "passed", report.Summary.Passed, "failed", report.Summary.Failed, "fixed", report.Summary.Fixed)
+		publishGitHub(cmd, report)
+		if report.Interrupted {
+			fmt.Fprintln(os.Stderr, "Run interrupted; partial report written.")
+			os.Exit(exitInterrupted)
 		}
-		log.Println("All processes completed.")
+		exitForReport("cli.rerun", repoID, "", report, gate)
 	},
 }
 
+// loadRepoConfig validates the OIDC token and fetches the repo's test config.
+// skipOIDC is for local development, where no CI token exists.
+func loadRepoConfig(ctx context.Context, repoID string, oidcToken string, skipOIDC bool) (toolkit.UnittestConfig, error) {
+	if skipOIDC {
+		slog.Warn("runner: oidc validation disabled", "repo_id", repoID)
+	} else {
+		valid, err := toolkit.SynraxOIDCCaller(ctx, repoID, oidcToken)
+		if err != nil {
+			return toolkit.UnittestConfig{}, err
+		}
+		if !valid {
+			return toolkit.UnittestConfig{}, errors.New("passed invalid OIDC token")
+		}
+	}
+
+	config, err := toolkit.SynraxConfigCaller(ctx, repoID)
+	if err != nil {
+		return toolkit.UnittestConfig{}, fmt.Errorf("runner: config fetch failed repo_id=%s error=%w", repoID, err)
+	}
+	slog.Info("runner: config fetched", "repo_id", repoID)
+	return config, nil
+}
+
+var errStorage = errors.New("storage")
+
+// loadBaseline reads the baseline selected by --baseline or
+// --baseline-from-storage; nil when neither is set. A branch without a stored
+// run yields an empty baseline, so every case counts as a new test.
+func loadBaseline(cmd *cobra.Command, repoID string, targetBranch string) (*reporter.Baseline, error) {
+	path, _ := cmd.Flags().GetString("baseline")
+	fromStorage, _ := cmd.Flags().GetBool("baseline-from-storage")
+	switch {
+	case path != "" && fromStorage:
+		return nil, errors.New("--baseline and --baseline-from-storage are mutually exclusive")
+	case path != "":
+		return reporter.LoadBaseline(path)
+	case fromStorage:
+		report, found, err := toolkit.SynraxBaselineCaller(cmd.Context(), repoID, targetBranch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fetch baseline: %w", errStorage, err)
+		}
+		if !found {
+			slog.Info("cli.baseline: no stored run, every case is new", "repo_id", repoID, "target_branch", targetBranch)
+		}
+		return &reporter.Baseline{Report: report, Source: "storage"}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// runOptions reads the case selection flags shared by commands that execute tests.
+func runOptions(cmd *cobra.Command) (reporter.Options, error) {
+	var opts reporter.Options
+	var err error
+	flags := cmd.Flags()
+	if opts.Filter.Tags, err = flags.GetStringSlice("tags"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.ExcludeTags, err = flags.GetStringSlice("exclude-tags"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.Endpoint, err = flags.GetString("endpoint"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.Methods, err = flags.GetStringSlice("method"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.TestID, err = flags.GetString("test-id"); err != nil {
+		return opts, err
+	}
+	if opts.Output.Dir, err = flags.GetString("out-dir"); err != nil {
+		return opts, err
+	}
+	if opts.Output.JSONName, err = flags.GetString("json-name"); err != nil {
+		return opts, err
+	}
+	if opts.Output.MarkdownName, err = flags.GetString("md-name"); err != nil {
+		return opts, err
+	}
+	if opts.Output.HTMLName, err = flags.GetString("html-name"); err != nil {
+		return opts, err
+	}
+	if opts.Output.Formats, err = flags.GetStringSlice("format"); err != nil {
+		return opts, err
+	}
+	if err := opts.Output.Validate(); err != nil {
+		return opts, err
+	}
+	if opts.Stream, err = streamOptions(cmd); err != nil {
+		return opts, err
+	}
+	if opts.Commit, err = flags.GetString("commit"); err != nil {
+		return opts, err
+	}
+	if opts.Commit == "" {
+		opts.Commit = os.Getenv("GITHUB_SHA")
+	}
+	return opts, opts.Filter.Validate()
+}
+
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("tags", nil, "only run cases tagged with any of these tags (smoke,auth)")
+	cmd.Flags().StringSlice("exclude-tags", nil, "skip cases tagged with any of these tags (slow,destructive)")
+	cmd.Flags().String("endpoint", "", "only run endpoints matching this glob, * spans path segments (/v1/admin/*)")
+	cmd.Flags().StringSlice("method", nil, "only run endpoints with these HTTP methods (GET,POST)")
+	cmd.Flags().String("test-id", "", "only run cases whose test id matches this regular expression")
+}
+
+// addIdentityFlags registers the flags that used to be positional arguments.
+func addIdentityFlags(cmd *cobra.Command) {
+	cmd.Flags().String("repo-id", "", "repository id used to fetch the app configuration")
+	cmd.Flags().String("oidc-token", "", "CI OIDC token; prefer SYNRAX_OIDC_TOKEN, flags show up in the process list")
+	cmd.Flags().Bool("no-oidc", false, "skip OIDC token validation, for local development")
+	addConfigFlag(cmd)
+}
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String("out-dir", "synrax", "directory the reports are written to, created when missing")
+	cmd.Flags().String("json-name", "report.json", "file name of the JSON report inside --out-dir")
+	cmd.Flags().String("md-name", "report.md", "file name of the Markdown report inside --out-dir")
+	cmd.Flags().String("html-name", "report.html", "file name of the HTML report inside --out-dir")
+	cmd.Flags().String("commit", "", "commit SHA recorded in the reports and metrics (default: GITHUB_SHA)")
+	cmd.Flags().StringSlice("format", []string{reporter.FormatJSON, reporter.FormatMarkdown, reporter.FormatHTML}, "report formats to write (json,md,html); rerun needs json")
+}
+
+// streamOptions opens the progress stream selected by --stream; nil when off.
+func streamOptions(cmd *cobra.Command) (*reporter.Stream, error) {
+	format, _ := cmd.Flags().GetString("stream")
+	path, _ := cmd.Flags().GetString("stream-file")
+	if format == "" {
+		return nil, nil
+	}
+	stream := &reporter.Stream{Format: format, Writer: os.Stdout}
+	if err := stream.Validate(); err != nil {
+		return nil, err
+	}
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("open stream file: %w", err)
+		}
+		stream.Writer = f // closed by the process exit, writes are unbuffered
+	}
+	return stream, nil
+}
+
+func addStreamFlags(cmd *cobra.Command) {
+	cmd.Flags().String("stream", "", "stream progress while the run executes: ndjson (one event per line) or tap (TAP version 13)")
+	cmd.Flags().String("stream-file", "-", "file the --stream output is written to, - for stdout")
+}
+
 func init() { // runs automatically at start (go thing)
+	addIdentityFlags(readDocs)
+	addLogFlags(readDocs)
+	readDocs.Flags().String("docs", "", "API documentation the test spec is generated from")
+	readDocs.Flags().String("branch", "", "target branch the metrics are stored under")
+	readDocs.Flags().Bool("no-store", false, "do not submit metrics, for local development")
+	readDocs.Flags().Bool("upload-cases", false, "also upload every case result, bodies redacted and capped, linked to the run id")
+	addOutboxFlag(readDocs)
+	addFilterFlags(readDocs)
+	addOutputFlags(readDocs)
+	addStreamFlags(readDocs)
+	addGitHubFlags(readDocs)
+	readDocs.Flags().String("baseline", "", "previous report.json to compare this run against")
+	readDocs.Flags().Bool("baseline-from-storage", false, "compare against the last run of repo_id and branch_name stored with --upload-cases")
+	addGateFlags(readDocs)
 	rootCommand.AddCommand(readDocs)
+
+	addIdentityFlags(rerunFailed)
+	addLogFlags(rerunFailed)
+	addFilterFlags(rerunFailed)
+	addOutputFlags(rerunFailed)
+	addStreamFlags(rerunFailed)
+	addGitHubFlags(rerunFailed)
+	addGateFlags(rerunFailed)
+	rerunFailed.Flags().String("from", "", "previous report.json to rerun failed cases from (default: the JSON report in --out-dir)")
+	rerunFailed.Flags().String("baseline", "", "previous report.json to compare the merged report against")
+	rootCommand.AddCommand(rerunFailed)
+
+	addConfigFlag(syncOutbox)
+	addLogFlags(syncOutbox)
+	addOutboxFlag(syncOutbox)
+	rootCommand.AddCommand(syncOutbox)
 }
 
 func Execute() {
+	// SIGINT/SIGTERM cancel the run; a second signal falls back to the default and kills the process
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Warn("cli.execute: received signal, cancelling run", "signal", sig.String())
+		signal.Stop(signals)
+		cancel()
+	}()
 
-	log.Printf("cli.execute: running root command")
-	if err := rootCommand.Execute(); err != nil {
-		log.Printf("cli.execute: root command failed error=%v", err)
+	slog.Debug("cli.execute: running root command")
+	if err := rootCommand.ExecuteContext(ctx); err != nil {
+		slog.Error("cli.execute: root command failed", "error", err)
 		fmt.Fprintf(os.Stderr, "An error occurred initializing main CLI execution.")
-		os.Exit(1)
+		os.Exit(exitConfig)
 	}
-	log.Printf("cli.execute: root command completed")
+	slog.Debug("cli.execute: root command completed")
 }
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"synrax/reporter"
+	"synrax/toolkit"
+
+	"github.com/spf13/cobra"
+)
+
+// Process exit codes, stable so CI can branch on them.
+const (
+	exitOK          = 0
+	exitTestsFailed = 1   // the run completed and the gate failed
+	exitConfig      = 2   // bad arguments, flags, OIDC token or repo config
+	exitSpec        = 3   // the server could not generate a test spec
+	exitStorage     = 4   // metrics, the stored baseline or the Synrax API could not be reached
+	exitRunError    = 5   // anything else, report writing included
+	exitInterrupted = 130 // cancelled by a signal, partial report written
+)
+
+// exitWith prints err for the user and exits with code.
+func exitWith(code int, err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(code)
+}
+
+// runErrorCode maps an error returned by the reporter to an exit code.
+func runErrorCode(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, reporter.ErrSpec):
+		return exitSpec
+	case errors.Is(err, reporter.ErrConfig):
+		return exitConfig
+	default:
+		return exitRunError
+	}
+}
+
+// apiErrorCode maps an error of a Synrax API call. A call the signal
+// cancelled exits exitInterrupted and an unreachable API exitStorage, so a
+// network error is not reported as bad config; anything else exits code.
+func apiErrorCode(err error, code int) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, toolkit.ErrUnavailable):
+		return exitStorage
+	default:
+		return code
+	}
+}
+
+// Values of --fail-on.
+const (
+	failOnNone = "none" // only the thresholds decide
+	failOnAny  = "any"  // fail on any failed or errored case
+	failOnNew  = "new"  // fail only on failures the baseline does not know about
+)
+
+// gatePolicy decides whether a completed run fails CI.
+type gatePolicy struct {
+	failOn      string
+	failUnder   float32 // minimum SuccessRate in percent, negative when unset
+	maxFailures int     // failed plus errored cases allowed, negative when unset
+}
+
+// gateOptions reads --fail-on, --fail-under and --max-failures. Without an
+// explicit --fail-on, a threshold replaces the default of failing on any case.
+func gateOptions(cmd *cobra.Command) (gatePolicy, error) {
+	flags := cmd.Flags()
+	var g gatePolicy
+	var err error
+	if g.failOn, err = flags.GetString("fail-on"); err != nil {
+		return g, err
+	}
+	if g.failUnder, err = flags.GetFloat32("fail-under"); err != nil {
+		return g, err
+	}
+	if g.maxFailures, err = flags.GetInt("max-failures"); err != nil {
+		return g, err
+	}
+
+	switch g.failOn {
+	case failOnNone, failOnAny, failOnNew:
+	default:
+		return g, fmt.Errorf("--fail-on must be one of %s, %s or %s, got %q", failOnNone, failOnAny, failOnNew, g.failOn)
+	}
+	if g.failUnder > 100 {
+		return g, fmt.Errorf("--fail-under is a percentage between 0 and 100, got %v", g.failUnder)
+	}
+	if !flags.Changed("fail-on") && (flags.Changed("fail-under") || flags.Changed("max-failures")) {
+		g.failOn = failOnNone
+	}
+	return g, nil
+}
+
+// check returns why the run fails the gate, or "" when it passes.
+func (g gatePolicy) check(report toolkit.UnittestReport, metrics toolkit.ReportMetric) string {
+	failures := report.Summary.Failed + report.Summary.Errored
+	switch {
+	case g.failOn == failOnAny && failures > 0:
+		return fmt.Sprintf("%d cases failed or errored.", failures)
+	case g.failOn == failOnNew && report.Baseline != nil && report.Baseline.Regressions() > 0:
+		return fmt.Sprintf("%d new failures compared with %s.", report.Baseline.Regressions(), report.Baseline.Source)
+	case g.maxFailures >= 0 && failures > g.maxFailures:
+		return fmt.Sprintf("%d cases failed or errored, more than --max-failures %d.", failures, g.maxFailures)
+	case g.failUnder >= 0 && metrics.SuccessRate < g.failUnder:
+		return fmt.Sprintf("Success rate %.1f%% is under --fail-under %.1f%%.", metrics.SuccessRate, g.failUnder)
+	}
+	return ""
+}
+
+// exitForReport exits with exitTestsFailed when the report fails the gate.
+func exitForReport(scope string, repoID string, targetBranch string, report toolkit.UnittestReport, g gatePolicy) {
+	metrics, err := toolkit.ReportMetrics(repoID, targetBranch, report)
+	if err != nil {
+		slog.Error(scope+": metrics failed", "repo_id", repoID, "error", err)
+		exitWith(exitRunError, err)
+	}
+	if reason := g.check(report, metrics); reason != "" {
+		slog.Error(scope+": gate failed", "repo_id", repoID, "fail_on", g.failOn, "success_rate", metrics.SuccessRate, "reason", reason)
+		fmt.Fprintln(os.Stderr, reason)
+		os.Exit(exitTestsFailed)
+	}
+}
+
+func addGateFlags(cmd *cobra.Command) {
+	cmd.Flags().String("fail-on", failOnAny, "exit 1 on: any (failed or errored cases), new (failures missing from the baseline) or none")
+	cmd.Flags().Float32("fail-under", -1, "exit 1 when the success rate in percent is below this value")
+	cmd.Flags().Int("max-failures", -1, "exit 1 when more than N cases fail or error")
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"synrax/toolkit"
+)
+
+func TestGateOptions(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		want    gatePolicy
+		wantErr string
+	}{
+		{want: gatePolicy{failOn: failOnAny, failUnder: -1, maxFailures: -1}},
+		{flags: []string{"--fail-on", "new"}, want: gatePolicy{failOn: failOnNew, failUnder: -1, maxFailures: -1}},
+		// a threshold replaces the default of failing on any case
+		{flags: []string{"--fail-under", "90"}, want: gatePolicy{failOn: failOnNone, failUnder: 90, maxFailures: -1}},
+		{flags: []string{"--max-failures", "2"}, want: gatePolicy{failOn: failOnNone, failUnder: -1, maxFailures: 2}},
+		{flags: []string{"--fail-on", "any", "--max-failures", "2"}, want: gatePolicy{failOn: failOnAny, failUnder: -1, maxFailures: 2}},
+		{flags: []string{"--fail-on", "some"}, wantErr: "--fail-on must be one of"},
+		{flags: []string{"--fail-under", "101"}, wantErr: "between 0 and 100"},
+	}
+	for _, tt := range tests {
+		cmd := &cobra.Command{Use: "test"}
+		addGateFlags(cmd)
+		if err := cmd.Flags().Parse(tt.flags); err != nil {
+			t.Fatal(err)
+		}
+		got, err := gateOptions(cmd)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%v: error %v, want %q", tt.flags, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%v: got %+v err %v, want %+v", tt.flags, got, err, tt.want)
+		}
+	}
+}
+
+func TestGatePolicyCheck(t *testing.T) {
+	onAny := gatePolicy{failOn: failOnAny, failUnder: -1, maxFailures: -1}
+	onNone := gatePolicy{failOn: failOnNone, failUnder: -1, maxFailures: -1}
+	onNew := gatePolicy{failOn: failOnNew, failUnder: -1, maxFailures: -1}
+	with := func(g gatePolicy, failUnder float32, maxFailures int) gatePolicy {
+		g.failUnder, g.maxFailures = failUnder, maxFailures
+		return g
+	}
+	tests := []struct {
+		name        string
+		gate        gatePolicy
+		summary     toolkit.UnittestSummary
+		regressions int // with a baseline when >= 0
+		successRate float32
+		want        string // prefix of the reason, "" when the gate passes
+	}{
+		{"any passes", onAny, toolkit.UnittestSummary{Passed: 3}, -1, 100, ""},
+		{"any failed", onAny, toolkit.UnittestSummary{Passed: 2, Failed: 1}, -1, 66, "1 cases failed or errored"},
+		{"any errored", onAny, toolkit.UnittestSummary{Passed: 2, Errored: 1}, -1, 66, "1 cases failed or errored"},
+		{"any flaky passes", onAny, toolkit.UnittestSummary{Passed: 2, Flaky: 1}, -1, 100, ""},
+		{"any skipped passes", onAny, toolkit.UnittestSummary{Passed: 2, Skipped: 4}, -1, 100, ""},
+		{"none ignores failures", onNone, toolkit.UnittestSummary{Failed: 5}, -1, 0, ""},
+		{"new without regressions", onNew, toolkit.UnittestSummary{Failed: 2}, 0, 0, ""},
+		{"new with regressions", onNew, toolkit.UnittestSummary{Failed: 2}, 1, 0, "1 new failures"},
+		{"new without baseline", onNew, toolkit.UnittestSummary{Failed: 2}, -1, 0, ""},
+		{"max failures reached", with(onNone, -1, 2), toolkit.UnittestSummary{Failed: 1, Errored: 1}, -1, 50, ""},
+		{"max failures exceeded", with(onNone, -1, 2), toolkit.UnittestSummary{Failed: 2, Errored: 1}, -1, 40, "3 cases failed or errored, more than --max-failures 2"},
+		{"max failures zero", with(onNone, -1, 0), toolkit.UnittestSummary{Errored: 1}, -1, 90, "1 cases failed or errored, more than"},
+		{"fail under met", with(onNone, 80, -1), toolkit.UnittestSummary{Failed: 1}, -1, 80, ""},
+		{"fail under missed", with(onNone, 80, -1), toolkit.UnittestSummary{Failed: 1}, -1, 79.5, "Success rate 79.5% is under --fail-under 80.0%"},
+		// the first failing rule gives the reason
+		{"any before thresholds", with(onAny, 90, 0), toolkit.UnittestSummary{Failed: 1}, -1, 50, "1 cases failed or errored."},
+		{"new before thresholds", with(onNew, 90, 0), toolkit.UnittestSummary{Failed: 1}, 1, 50, "1 new failures"},
+		{"max failures before fail under", with(onNone, 90, 0), toolkit.UnittestSummary{Failed: 1}, -1, 50, "1 cases failed or errored, more than"},
+		{"thresholds with any", with(onAny, 90, -1), toolkit.UnittestSummary{Passed: 1, Flaky: 1}, -1, 85, "Success rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := toolkit.UnittestReport{Summary: tt.summary}
+			if tt.regressions >= 0 {
+				report.Baseline = &toolkit.BaselineComparison{Source: "storage", NewFailures: tt.regressions}
+			}
+			got := tt.gate.check(report, toolkit.ReportMetric{SuccessRate: tt.successRate})
+			if tt.want == "" && got != "" || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("invalid OIDC token"), exitConfig},
+		{fmt.Errorf("config: %w", toolkit.ErrUnavailable), exitStorage},
+		{fmt.Errorf("%w: %w", toolkit.ErrUnavailable, context.Canceled), exitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := apiErrorCode(tt.err, exitConfig); got != tt.want {
+			t.Errorf("%v: got %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// rerunReport writes a report whose single case has outcome, against a
+// target nothing listens on, so a rerun of the case fails.
+func rerunReport(t *testing.T, dir string, outcome string) string {
+	t.Helper()
+	spec := toolkit.TestSpec{BaseURL: "http://127.0.0.1:1", Endpoints: []toolkit.Endpoint{{
+		Name: "/items", Method: "GET",
+		Tests: []toolkit.Test{{ID: "a", Expectation: toolkit.Expectation{Status: []int{200}}}},
+	}}}
+	report := toolkit.UnittestReport{Spec: &spec, Results: []toolkit.UnittestCaseResult{{
+		Endpoint: "/items", Method: "GET", TestID: "a", Outcome: outcome, Passed: outcome == toolkit.OutcomePassed,
+	}}}
+	raw, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "previous.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExitCodes(t *testing.T) {
+	const validConfig = `{"base":"http://127.0.0.1:1"}`
+	tests := []struct {
+		name    string
+		outcome string // of the case in the previous report
+		config  string // config response body, a 5xx status when it is a number
+		oidc    string // OIDC response body, --no-oidc when empty
+		down    bool   // the API does not listen
+		flags   []string
+		want    int
+	}{
+		{name: "passed", outcome: toolkit.OutcomePassed, want: exitOK},
+		{name: "failed", outcome: toolkit.OutcomeFailed, want: exitTestsFailed},
+		{name: "fail on none", outcome: toolkit.OutcomeFailed, flags: []string{"--fail-on", "none"}, want: exitOK},
+		{name: "max failures kept", outcome: toolkit.OutcomeFailed, flags: []string{"--max-failures", "1"}, want: exitOK},
+		{name: "max failures exceeded", outcome: toolkit.OutcomeFailed, flags: []string{"--max-failures", "0"}, want: exitTestsFailed},
+		{name: "fail under", outcome: toolkit.OutcomeFailed, flags: []string{"--fail-under", "50"}, want: exitTestsFailed},
+		{name: "bad gate flag", outcome: toolkit.OutcomePassed, flags: []string{"--fail-on", "some"}, want: exitConfig},
+		{name: "config missing", outcome: toolkit.OutcomePassed, config: `{"status":"success","response":null}`, want: exitConfig},
+		{name: "config 503", outcome: toolkit.OutcomePassed, config: "503", want: exitStorage},
+		{name: "api down", outcome: toolkit.OutcomePassed, down: true, want: exitStorage},
+		{name: "oidc rejected", outcome: toolkit.OutcomePassed, oidc: `{"status":"failure","reason":"expired"}`, want: exitConfig},
+		{name: "oidc 502", outcome: toolkit.OutcomePassed, oidc: "502", want: exitStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respond := func(w http.ResponseWriter, body string) {
+				var status int
+				if _, err := fmt.Sscan(body, &status); err == nil {
+					w.WriteHeader(status)
+					return
+				}
+				w.Write([]byte(body))
+			}
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/github/oidc_validate":
+					respond(w, tt.oidc)
+				case "/db/read":
+					respond(w, cmp.Or(tt.config, validConfig))
+				}
+			}))
+			t.Cleanup(api.Close)
+			if tt.down {
+				api.Close()
+			}
+
+			dir := t.TempDir()
+			args := []string{"rerun", "--repo-id", "r", "--from", rerunReport(t, dir, tt.outcome), "--quiet"}
+			if tt.oidc == "" {
+				args = append(args, "--no-oidc")
+			} else {
+				args = append(args, "--oidc-token", "token")
+			}
+			cmd := cliCommand(dir, api.URL, append(args, tt.flags...)...)
+			out, err := cmd.CombinedOutput()
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("exit code %d, want %d\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
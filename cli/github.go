@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"synrax/toolkit"
+
+	"github.com/spf13/cobra"
+)
+
+// publishGitHub writes the step summary and annotations inside GitHub
+// Actions and upserts the PR comment when asked. The comment token comes from
+// GITHUB_TOKEN only, never from a flag that shows up in the process list.
+// Failures are reported but never change the exit code; a fork PR without
+// comment permission must not hide the test result.
+func publishGitHub(cmd *cobra.Command, report toolkit.UnittestReport) {
+	flags := cmd.Flags()
+	noGitHub, _ := flags.GetBool("no-github")
+	comment, _ := flags.GetBool("github-comment")
+
+	if toolkit.InGitHubActions() && !noGitHub {
+		if os.Getenv("GITHUB_STEP_SUMMARY") != "" {
+			if err := toolkit.GitHubStepSummary(report); err != nil {
+				slog.Warn("cli.github: step summary failed", "error", err)
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		// stderr: stdout may carry the --stream output, and the runner reads
+		// workflow commands from both
+		if err := toolkit.GitHubAnnotations(os.Stderr, report); err != nil {
+			slog.Warn("cli.github: annotations failed", "error", err)
+		}
+	}
+
+	if !comment {
+		return
+	}
+	var target toolkit.GitHubComment
+	target.APIURL, _ = flags.GetString("github-api-url")
+	target.PR, _ = flags.GetInt("github-pr")
+	target = toolkit.GitHubCommentFromEnv(target)
+	if err := toolkit.GitHubUpsertComment(target, report); err != nil {
+		slog.Warn("cli.github: comment failed", "repository", target.Repository, "pr", target.PR, "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	slog.Info("cli.github: comment upserted", "repository", target.Repository, "pr", target.PR)
+}
+
+func addGitHubFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-github", false, "inside GitHub Actions, do not write the step summary and ::error annotations")
+	cmd.Flags().Bool("github-comment", false, "create or update a single PR comment with the report")
+	cmd.Flags().String("github-api-url", "", "GitHub API base URL (default: GITHUB_API_URL, then https://api.github.com)")
+	cmd.Flags().Int("github-pr", 0, "pull request to comment on (default: read from the workflow event); the token is read from GITHUB_TOKEN only")
+}
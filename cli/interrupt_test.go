@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptDuringSpecFetch(t *testing.T) {
+	specRequested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db/read":
+			w.Write([]byte(`{"base":"http://127.0.0.1:1"}`))
+		case "/ai/test_spec":
+			specRequested <- struct{}{}
+			select { // the spec is never answered while the test runs
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	t.Cleanup(api.Close)
+	t.Cleanup(func() { close(release) }) // runs before the server closes
+
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs.txt")
+	if err := os.WriteFile(docs, []byte("GET /items"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := cliCommand(dir, api.URL, "read", "--repo-id", "r", "--docs", docs, "--no-oidc", "--no-store", "--quiet")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case <-specRequested:
+	case err := <-exited:
+		t.Fatalf("exited before the spec fetch: %v", err)
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("spec was never requested")
+	}
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-exited:
+		if code := exitCode(err); code != exitInterrupted {
+			t.Errorf("exit code %d (%v), want %d", code, err, exitInterrupted)
+		}
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("still waiting for the spec 5s after SIGINT")
+	}
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"synrax/toolkit"
+)
+
+// setupLogging installs the logger selected by the log flags. Every record of
+// the command carries its name and, unless empty, the run id. It runs after
+// resolveSettings, so the flags may also come from SYNRAX_LOG_LEVEL or
+// synrax.yaml.
+func setupLogging(cmd *cobra.Command, runID string) error {
+	flags := cmd.Flags()
+	level, _ := flags.GetString("log-level")
+	format, _ := flags.GetString("log-format")
+	quiet, _ := flags.GetBool("quiet")
+	attrs := []any{"command", cmd.Name()}
+	if runID != "" {
+		attrs = append(attrs, "run_id", runID)
+	}
+	return toolkit.SetupLogging(os.Stderr, toolkit.LogOptions{Level: level, Format: format, Quiet: quiet}, attrs...)
+}
+
+func addLogFlags(cmd *cobra.Command) {
+	cmd.Flags().String("log-level", "info", "minimum log level: debug, info, warn or error; debug adds request URLs and response bodies")
+	cmd.Flags().String("log-format", toolkit.LogFormatText, "log record format: text or json")
+	cmd.Flags().BoolP("quiet", "q", false, "only log errors")
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// cliArgsEnv carries the arguments of a CLI run in a child process: tests
+// re-execute the test binary so signals and os.Exit stay out of the test
+// process. Arguments are separated by newlines.
+const cliArgsEnv = "SYNRAX_TEST_CLI_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(cliArgsEnv); ok {
+		os.Args = append([]string{"synrax"}, strings.Split(args, "\n")...)
+		Execute()
+		os.Exit(exitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// cliCommand returns the CLI run with args in dir against the Synrax API at
+// apiURL. Settings from the environment of the test are not passed on.
+func cliCommand(dir string, apiURL string, args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = dir
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "SYNRAX_") && !strings.HasPrefix(kv, "GITHUB_") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		cliArgsEnv+"="+strings.Join(args, "\n"),
+		"SYNRAX_API_BASE_URL="+apiURL,
+	)
+	return cmd
+}
+
+// exitCode is the exit code of a finished child, -1 when it did not exit.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
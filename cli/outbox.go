@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"synrax/toolkit"
+)
+
+var syncOutbox = &cobra.Command{
+	Use:   "sync [--outbox DIR]",
+	Short: "Retries the metric submissions saved in the outbox",
+	Long:  "Submits every entry `read` saved in --outbox because the storage API failed, and removes the ones that are stored. Rows the API already has are not stored twice: every create names the unique key of its table, the run id for metrics. Exits 4 while entries are still pending. Flags fall back to SYNRAX_<FLAG> and synrax.yaml like `read`.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := resolveSettings(cmd, args); err != nil {
+			slog.Error("cli.sync: invalid settings", "error", err)
+			exitWith(exitConfig, err)
+		}
+		// no run id: the entries carry their own
+		if err := setupLogging(cmd, ""); err != nil {
+			exitWith(exitConfig, err)
+		}
+		dir, _ := cmd.Flags().GetString("outbox")
+		if dir == "" {
+			exitWith(exitConfig, fmt.Errorf("--outbox is empty"))
+		}
+
+		synced, pending, err := toolkit.SyncOutbox(cmd.Context(), dir)
+		if err != nil {
+			slog.Error("cli.sync: failed", "outbox", dir, "error", err)
+			exitWith(apiErrorCode(err, exitStorage), err)
+		}
+		slog.Info("cli.sync: completed", "outbox", dir, "synced", synced, "pending", pending)
+		fmt.Printf("Synced %d, pending %d.\n", synced, pending)
+		if pending > 0 {
+			exitWith(exitStorage, fmt.Errorf("%d submissions still pending in %s", pending, dir))
+		}
+	},
+}
+
+// storeRun submits the metric and, with uploadCases, the case rows of report.
+// When that fails and outbox is set, the submission is saved there for `sync`
+// and storeRun returns nil: the tests ran and their result decides the exit
+// code, not the storage API. A cancelled ctx is returned after the save.
+func storeRun(ctx context.Context, repoID string, targetBranch string, report toolkit.UnittestReport, uploadCases bool, outbox string) error {
+	metric, err := toolkit.ReportMetrics(repoID, targetBranch, report)
+	if err != nil {
+		return err
+	}
+	var cases []toolkit.CaseRecord
+	if uploadCases {
+		cases = toolkit.CaseRecords(repoID, targetBranch, report)
+		metric.CasesStored = true
+	}
+
+	err = toolkit.SynraxRunStorage(ctx, metric, cases)
+	if err == nil {
+		return nil
+	}
+	slog.Error("cli.submission: failed", "repo_id", repoID, "id", metric.ID, "error", err)
+	if outbox == "" {
+		return err
+	}
+
+	now := time.Now().UTC()
+	path, werr := toolkit.WriteOutbox(outbox, toolkit.OutboxEntry{
+		ID:          metric.ID,
+		CreatedAt:   now,
+		Attempts:    1,
+		LastAttempt: now,
+		LastError:   err.Error(),
+		Metric:      metric,
+		Cases:       cases,
+	})
+	if werr != nil {
+		return fmt.Errorf("%v; saving to outbox failed: %w", err, werr)
+	}
+	slog.Warn("cli.submission: saved to outbox", "repo_id", repoID, "id", metric.ID, "path", path)
+	fmt.Fprintf(os.Stderr, "Metrics not stored (%v); saved to %s, run `sync` to retry.\n", err, path)
+	return ctx.Err() // a signal still ends the process as interrupted
+}
+
+func addOutboxFlag(cmd *cobra.Command) {
+	cmd.Flags().String("outbox", ".synrax/outbox", "directory failed submissions are saved to and `sync` retries from; empty makes a storage failure exit 4")
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings resolve per flag, first match wins:
+//
+//  1. the command line flag (or a deprecated positional argument)
+//  2. the environment variable SYNRAX_<FLAG>, e.g. SYNRAX_REPO_ID for --repo-id
+//  3. the key of the same name in synrax.yaml, e.g. repo_id or repo-id
+//  4. the flag default
+//
+// The project file is ./synrax.yaml, or the one named by --config or
+// SYNRAX_CONFIG. Secrets are not read from the project file.
+const defaultProjectFile = "synrax.yaml"
+
+var secretFlags = map[string]bool{"oidc-token": true}
+
+// envName maps a flag to its environment variable.
+func envName(flag string) string {
+	return "SYNRAX_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// resolveSettings fills every flag that was not set on the command line from
+// the environment and the project file. positional names the flags that the
+// deprecated positional arguments stand for, in order.
+func resolveSettings(cmd *cobra.Command, args []string, positional ...string) error {
+	flags := cmd.Flags()
+	if len(args) > 0 {
+		slog.Warn("cli.settings: positional arguments are deprecated, use flags", "flags", "--"+strings.Join(positional, ", --"))
+		for i, arg := range args {
+			if flags.Changed(positional[i]) {
+				return fmt.Errorf("--%s given both as flag and as positional argument", positional[i])
+			}
+			if err := flags.Set(positional[i], arg); err != nil {
+				return err
+			}
+		}
+	}
+
+	path, explicit := defaultProjectFile, false
+	if flags.Changed("config") {
+		path, _ = flags.GetString("config")
+		explicit = true
+	} else if v, ok := os.LookupEnv(envName("config")); ok && v != "" {
+		path, explicit = v, true
+	}
+	project, err := readProjectFile(path, explicit)
+	if err != nil {
+		return err
+	}
+	for key := range project {
+		f := flags.Lookup(key)
+		switch {
+		case key == "config" || (f == nil && !knownProjectKey(key)):
+			return fmt.Errorf("%s: unknown key %q", path, key)
+		case secretFlags[key]:
+			return fmt.Errorf("%s: %q must not be stored in the project file, use %s", path, key, envName(key))
+		}
+	}
+
+	var setErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if setErr != nil || f.Changed || f.Name == "config" || f.Name == "help" {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			slog.Debug("cli.settings: flag resolved", "flag", f.Name, "source", "env")
+			if err := flags.Set(f.Name, v); err != nil {
+				setErr = fmt.Errorf("%s: %w", envName(f.Name), err)
+			}
+			return
+		}
+		if v, ok := project[f.Name]; ok {
+			slog.Debug("cli.settings: flag resolved", "flag", f.Name, "source", path)
+			if err := flags.Set(f.Name, v); err != nil {
+				setErr = fmt.Errorf("%s: %s: %w", path, f.Name, err)
+			}
+		}
+	})
+	return setErr
+}
+
+// readProjectFile returns the project file as flag name to flag value. A
+// missing default file is not an error.
+func readProjectFile(path string, explicit bool) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read project file: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parse project file %s: %w", path, err)
+	}
+	slog.Debug("cli.settings: loaded project file", "path", path, "keys", len(doc))
+
+	out := make(map[string]string, len(doc))
+	for key, v := range doc {
+		name := strings.ReplaceAll(key, "_", "-")
+		switch val := v.(type) {
+		case []any:
+			items := make([]string, len(val))
+			for i, item := range val {
+				items[i] = fmt.Sprint(item)
+			}
+			out[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: %q must be a scalar or a list", path, key)
+		case nil:
+			// `key:` with no value keeps the default
+		default:
+			out[name] = fmt.Sprint(val)
+		}
+	}
+	return out, nil
+}
+
+// knownProjectKey allows keys that belong to another command, so one project
+// file serves both read and rerun.
+func knownProjectKey(key string) bool {
+	for _, c := range rootCommand.Commands() {
+		if c.Flags().Lookup(key) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// requireFlags reports the first empty flag with where it can be set.
+func requireFlags(cmd *cobra.Command, names ...string) error {
+	for _, name := range names {
+		if v, _ := cmd.Flags().GetString(name); strings.TrimSpace(v) == "" {
+			return fmt.Errorf("--%s is required (or set %s)", name, envName(name))
+		}
+	}
+	return nil
+}
+
+func addConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().String("config", defaultProjectFile, "project file with defaults for these flags (env SYNRAX_CONFIG)")
+}
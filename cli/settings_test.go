@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func settingsCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	addConfigFlag(cmd)
+	cmd.Flags().String("repo-id", "default", "")
+	cmd.Flags().String("docs", "", "")
+	cmd.Flags().StringSlice("tags", nil, "")
+	cmd.Flags().Bool("no-store", false, "")
+	cmd.Flags().String("oidc-token", "", "")
+	return cmd
+}
+
+func TestResolveSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags      []string
+		positional []string
+		env        map[string]string
+		project    string // written to ./synrax.yaml when set
+		files      map[string]string
+		want       map[string]string // flag -> resolved value
+		wantErr    string
+	}{
+		{name: "default", want: map[string]string{"repo-id": "default"}},
+		{name: "project file", project: "repo_id: from-file\n", want: map[string]string{"repo-id": "from-file"}},
+		{
+			name: "env over project file", project: "repo-id: from-file\n",
+			env:  map[string]string{"SYNRAX_REPO_ID": "from-env"},
+			want: map[string]string{"repo-id": "from-env"},
+		},
+		{
+			name: "flag over env", flags: []string{"--repo-id", "from-flag"}, project: "repo_id: from-file\n",
+			env:  map[string]string{"SYNRAX_REPO_ID": "from-env"},
+			want: map[string]string{"repo-id": "from-flag"},
+		},
+		{
+			name: "positional", positional: []string{"from-arg", "docs.md"},
+			env:  map[string]string{"SYNRAX_REPO_ID": "from-env"},
+			want: map[string]string{"repo-id": "from-arg", "docs": "docs.md"},
+		},
+		{name: "positional and flag", flags: []string{"--repo-id", "x"}, positional: []string{"y"}, wantErr: "both as flag and as positional"},
+		{
+			name: "list and bool", project: "tags: [smoke, auth]\nno_store: true\ndocs:\n",
+			want: map[string]string{"tags": "[smoke,auth]", "no-store": "true", "docs": ""},
+		},
+		{name: "secret in project file", project: "oidc_token: x\n", wantErr: "must not be stored"},
+		{name: "unknown key", project: "repo: x\n", wantErr: `unknown key "repo"`},
+		{name: "nested value", project: "repo_id:\n  a: b\n", wantErr: "must be a scalar or a list"},
+		{
+			name: "config from env", env: map[string]string{"SYNRAX_CONFIG": "other.yaml"},
+			project: "repo_id: default-file\n", files: map[string]string{"other.yaml": "repo_id: other-file\n"},
+			want: map[string]string{"repo-id": "other-file"},
+		},
+		{name: "explicit config missing", flags: []string{"--config", "missing.yaml"}, wantErr: "read project file"},
+		{name: "bad env value", env: map[string]string{"SYNRAX_NO_STORE": "maybe"}, wantErr: "SYNRAX_NO_STORE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			for _, flag := range []string{"config", "repo-id", "docs", "tags", "no-store", "oidc-token"} {
+				t.Setenv(envName(flag), "")
+				os.Unsetenv(envName(flag))
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			files := map[string]string{defaultProjectFile: tt.project}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			for name, content := range files {
+				if content == "" {
+					continue
+				}
+				if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := settingsCommand()
+			if err := cmd.ParseFlags(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			err := resolveSettings(cmd, tt.positional, "repo-id", "docs")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for flag, want := range tt.want {
+				if got := cmd.Flags().Lookup(flag).Value.String(); got != want {
+					t.Errorf("--%s = %q, want %q", flag, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireFlags(t *testing.T) {
+	cmd := settingsCommand()
+	if err := cmd.ParseFlags([]string{"--docs", " "}); err != nil {
+		t.Fatal(err)
+	}
+	if err := requireFlags(cmd, "repo-id"); err != nil {
+		t.Errorf("repo-id has a default: %v", err)
+	}
+	err := requireFlags(cmd, "repo-id", "docs")
+	if err == nil || !strings.Contains(err.Error(), "SYNRAX_DOCS") {
+		t.Errorf("error %v, want one naming SYNRAX_DOCS", err)
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"synrax/cli"
@@ -10,31 +10,31 @@ import (
 )
 
 func init() {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-
 	localEnv, _ := filepath.Abs(".env")
 	if _, err := os.Stat(localEnv); err == nil {
 		_ = godotenv.Load(localEnv) // fallback in case of production failure
-		log.Printf("main.init: loaded env file path=%s", localEnv)
+		slog.Debug("main.init: loaded env file", "path", localEnv)
 	} else {
-		log.Printf("main.init: env file not found path=%s", localEnv)
+		slog.Debug("main.init: env file not found", "path", localEnv)
 	}
 }
 
 func main() {
-	log.Printf("main: starting CLI")
+	slog.Debug("main: starting CLI")
 	/*
 		Chain of operations:
 
 		1) Start the CLI
-		go run . read [id] [path] [oidc token]
+		go run . read --repo-id [id] --docs [path] --branch [branch]
 
 		where:
 		id - represents repository id
 		path - is the path to the documentation utilized by the system to create report
+		the OIDC token is read from SYNRAX_OIDC_TOKEN (or --oidc-token); any flag can
+		also come from SYNRAX_<FLAG> or synrax.yaml
 
 		example command:
-		go run . read 123 ./docs.txt
+		go run . read --repo-id 123 --docs ./docs.txt --no-oidc --no-store
 
 		2) `RunUnittest` will be ran which will:
 			- Fetch the repo `configurations` for the database with the given repo_id
@@ -0,0 +1,331 @@
+package reporter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"synrax/toolkit"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	authBearer            = "bearer"
+	authBasic             = "basic"
+	authAPIKey            = "api_key"
+	authClientCredentials = "oauth2_client_credentials"
+	authJWT               = "jwt"
+
+	// tokens are refreshed when they are this close to expiring
+	tokenRefreshLeeway = 30 * time.Second
+)
+
+var errAuth = errors.New("auth provider")
+
+// authProvider attaches credentials to an outgoing request. Credentials the
+// test already set explicitly are left untouched.
+type authProvider interface {
+	apply(ctx context.Context, req *http.Request) error
+}
+
+// authCache keeps one provider per identity so fetched and minted tokens are
+// reused until they expire.
+type authCache struct {
+	mu        sync.Mutex
+	client    *http.Client
+	providers map[string]authProvider
+}
+
+func newAuthCache(client *http.Client) *authCache {
+	return &authCache{client: client, providers: make(map[string]authProvider)}
+}
+
+func (c *authCache) provider(identity string, ac toolkit.AuthConfig) (authProvider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.providers[identity]; ok {
+		return p, nil
+	}
+	p, err := newAuthProvider(ac, c.client)
+	if err != nil {
+		return nil, err
+	}
+	c.providers[identity] = p
+	return p, nil
+}
+
+func newAuthProvider(ac toolkit.AuthConfig, client *http.Client) (authProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(ac.Type)) {
+	case "", authBearer:
+		return headerAuth{name: "Authorization", value: "Bearer " + ac.Token}, nil
+	case authBasic:
+		cred := base64.StdEncoding.EncodeToString([]byte(ac.Username + ":" + ac.Password))
+		return headerAuth{name: "Authorization", value: "Basic " + cred}, nil
+	case authAPIKey:
+		name := stringsTrimOrDefault(ac.Name, "X-API-Key")
+		switch strings.ToLower(stringsTrimOrDefault(ac.In, "header")) {
+		case "header":
+			return headerAuth{name: name, value: ac.APIKey}, nil
+		case "query":
+			return queryAuth{name: name, value: ac.APIKey}, nil
+		default:
+			return nil, fmt.Errorf("api_key: unknown location %q", ac.In)
+		}
+	case authClientCredentials:
+		if strings.TrimSpace(ac.TokenURL) == "" {
+			return nil, fmt.Errorf("oauth2_client_credentials: token_url is empty")
+		}
+		return &clientCredentialsAuth{cfg: ac, client: client}, nil
+	case authJWT:
+		return newJWTAuth(ac)
+	default:
+		return nil, fmt.Errorf("unknown auth type %q", ac.Type)
+	}
+}
+
+type headerAuth struct {
+	name  string
+	value string
+}
+
+func (h headerAuth) apply(_ context.Context, req *http.Request) error {
+	if req.Header.Get(h.name) == "" {
+		req.Header.Set(h.name, h.value)
+	}
+	return nil
+}
+
+type queryAuth struct {
+	name  string
+	value string
+}
+
+func (a queryAuth) apply(_ context.Context, req *http.Request) error {
+	q := req.URL.Query()
+	if q.Get(a.name) == "" {
+		q.Set(a.name, a.value)
+		req.URL.RawQuery = q.Encode()
+	}
+	return nil
+}
+
+// cachedToken is a bearer token that is renewed through fetch once it is
+// about to expire.
+type cachedToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (c *cachedToken) get(ctx context.Context, fetch func(context.Context) (string, time.Time, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expires.IsZero() || time.Until(c.expires) > tokenRefreshLeeway) {
+		return c.token, nil
+	}
+	token, expires, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, expires
+	return token, nil
+}
+
+func setBearer(req *http.Request, token string) {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+type clientCredentialsAuth struct {
+	cfg    toolkit.AuthConfig
+	client *http.Client
+	cached cachedToken
+}
+
+func (a *clientCredentialsAuth) apply(ctx context.Context, req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	token, err := a.cached.get(ctx, a.fetch)
+	if err != nil {
+		return err
+	}
+	setBearer(req, token)
+	return nil
+}
+
+func (a *clientCredentialsAuth) fetch(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(a.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.cfg.Scopes, " "))
+	}
+	useBody := strings.EqualFold(strings.TrimSpace(a.cfg.ClientAuth), "body")
+	if useBody {
+		form.Set("client_id", a.cfg.ClientID)
+		form.Set("client_secret", a.cfg.ClientSecret)
+	}
+
+	// the client has no global timeout, budgets are per request
+	ctx, cancel := context.WithTimeout(ctx, defaultTotalTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !useBody {
+		req.SetBasicAuth(url.QueryEscape(a.cfg.ClientID), url.QueryEscape(a.cfg.ClientSecret))
+	}
+
+	slog.Info("tester.auth: fetching client credentials token", "client_id", a.cfg.ClientID)
+	slog.Debug("tester.auth: token request", "url", a.cfg.TokenURL)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", time.Time{}, fmt.Errorf("oauth2 token request failed with status=%d body=%s", resp.StatusCode, truncate(string(body), 300))
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2 token decode: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("oauth2 token response has no access_token")
+	}
+	var expires time.Time
+	if tok.ExpiresIn > 0 {
+		expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	return tok.AccessToken, expires, nil
+}
+
+type jwtAuth struct {
+	cfg    toolkit.AuthConfig
+	alg    jwa.SignatureAlgorithm
+	key    jwk.Key
+	ttl    time.Duration
+	cached cachedToken
+}
+
+func newJWTAuth(ac toolkit.AuthConfig) (*jwtAuth, error) {
+	var alg jwa.SignatureAlgorithm
+	if err := alg.Accept(stringsTrimOrDefault(ac.Algorithm, "RS256")); err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	raw, err := os.ReadFile(ac.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: read key file: %w", err)
+	}
+
+	var key jwk.Key
+	if strings.HasPrefix(string(alg), "HS") {
+		key, err = jwk.FromRaw([]byte(strings.TrimSpace(string(raw))))
+	} else {
+		key, err = jwk.ParseKey(raw, jwk.WithPEM(true))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: parse key file: %w", err)
+	}
+	if ac.KeyID != "" {
+		if err := key.Set(jwk.KeyIDKey, ac.KeyID); err != nil {
+			return nil, fmt.Errorf("jwt: set key id: %w", err)
+		}
+	}
+
+	ttl := 300 * time.Second
+	if ac.TTLSeconds > 0 {
+		ttl = time.Duration(ac.TTLSeconds) * time.Second
+	}
+	return &jwtAuth{cfg: ac, alg: alg, key: key, ttl: ttl}, nil
+}
+
+func (a *jwtAuth) apply(ctx context.Context, req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	token, err := a.cached.get(ctx, a.mint)
+	if err != nil {
+		return err
+	}
+	setBearer(req, token)
+	return nil
+}
+
+func (a *jwtAuth) mint(context.Context) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(a.ttl)
+
+	b := jwt.NewBuilder().IssuedAt(now).NotBefore(now).Expiration(expires)
+	if a.cfg.Issuer != "" {
+		b = b.Issuer(a.cfg.Issuer)
+	}
+	if a.cfg.Subject != "" {
+		b = b.Subject(a.cfg.Subject)
+	}
+	if len(a.cfg.Audience) > 0 {
+		b = b.Audience(a.cfg.Audience)
+	}
+	for k, v := range a.cfg.Claims {
+		b = b.Claim(k, v)
+	}
+	tok, err := b.Build()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("jwt: build: %w", err)
+	}
+	signed, err := jwt.Sign(tok, jwt.WithKey(a.alg, a.key))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("jwt: sign: %w", err)
+	}
+	slog.Debug("tester.auth: minted jwt", "alg", a.alg, "expires", expires.UTC().Format(time.RFC3339))
+	return string(signed), expires, nil
+}
+
+// applyAuth attaches the credentials of the test's identity, unless the test
+// deliberately exercises missing or wrong credentials.
+func applyAuth(ctx context.Context, st *runState, req *http.Request, tc toolkit.Test) error {
+	if !shouldInjectAuth(tc.ID) {
+		return nil
+	}
+	identity, ac, err := resolveAuth(st.cfg, tc.As)
+	if err != nil || ac == nil {
+		return err
+	}
+	p, err := st.auth.provider(identity, *ac)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errAuth, err)
+	}
+	if err := p.apply(ctx, req); err != nil {
+		return fmt.Errorf("%w: %v", errAuth, err)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,298 @@
+package reporter
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"synrax/toolkit"
+)
+
+func TestCachedTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		expiresIn time.Duration // of the cached token, 0 for no expiry
+		wantFetch bool
+	}{
+		{name: "empty", wantFetch: true},
+		{name: "valid", token: "old", expiresIn: time.Hour},
+		{name: "no expiry", token: "old"},
+		{name: "inside leeway", token: "old", expiresIn: tokenRefreshLeeway - time.Second, wantFetch: true},
+		{name: "expired", token: "old", expiresIn: -time.Minute, wantFetch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cachedToken{token: tt.token}
+			if tt.expiresIn != 0 {
+				c.expires = time.Now().Add(tt.expiresIn)
+			}
+			fetched := 0
+			got, err := c.get(context.Background(), func(context.Context) (string, time.Time, error) {
+				fetched++
+				return "new", time.Now().Add(time.Hour), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "old"
+			if tt.wantFetch {
+				want = "new"
+			}
+			if got != want || (fetched == 1) != tt.wantFetch {
+				t.Errorf("token %q after %d fetches, want %q", got, fetched, want)
+			}
+		})
+	}
+
+	// a failed refresh returns the error and tries again on the next call
+	c := cachedToken{token: "old", expires: time.Now().Add(time.Second)}
+	fail := func(context.Context) (string, time.Time, error) { return "", time.Time{}, errors.New("down") }
+	if _, err := c.get(context.Background(), fail); err == nil {
+		t.Error("failed refresh returned no error")
+	}
+	if got, _ := c.get(context.Background(), func(context.Context) (string, time.Time, error) {
+		return "new", time.Time{}, nil
+	}); got != "new" {
+		t.Errorf("token %q after a failed refresh, want new", got)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientAuth string
+		status     int
+		body       string
+		wantErr    string
+	}{
+		{name: "basic", body: `{"access_token":"tok","expires_in":3600}`},
+		{name: "body", clientAuth: "body", body: `{"access_token":"tok"}`},
+		{name: "rejected", status: http.StatusUnauthorized, body: `{"error":"invalid_client"}`, wantErr: `status=401 body={"error":"invalid_client"}`},
+		{name: "not json", body: `<html>`, wantErr: "oauth2 token decode"},
+		{name: "no token", body: `{"token_type":"bearer"}`, wantErr: "no access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			token := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				r.ParseForm()
+				user, pass, basic := r.BasicAuth()
+				// RFC 6749 2.3.1 form-encodes the basic credentials
+				user, _ = url.QueryUnescape(user)
+				pass, _ = url.QueryUnescape(pass)
+				if tt.clientAuth == "body" {
+					user, pass = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+				}
+				if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "read write" ||
+					user != "id" || pass != "s&cret" || basic == (tt.clientAuth == "body") {
+					t.Errorf("token request form %v basic %v (%q, %q)", r.PostForm, basic, user, pass)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write([]byte(tt.body))
+			})
+			ac := toolkit.AuthConfig{
+				Type: authClientCredentials, TokenURL: token.URL, ClientID: "id", ClientSecret: "s&cret",
+				Scopes: []string{"read", "write"}, ClientAuth: tt.clientAuth,
+			}
+			p, err := newAuthProvider(ac, http.DefaultClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 2 {
+				req := httptest.NewRequest(http.MethodGet, "/items", nil)
+				err = p.apply(context.Background(), req)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("error %v, want %q", err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := req.Header.Get("Authorization"); got != "Bearer tok" {
+					t.Errorf("Authorization %q", got)
+				}
+			}
+			// a token is reused, a failed fetch is retried
+			if want := map[bool]int{true: 1, false: 2}[tt.wantErr == ""]; requests != want {
+				t.Errorf("%d token requests, want %d", requests, want)
+			}
+
+			// explicit credentials of the test are left alone
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.Header.Set("Authorization", "Bearer explicit")
+			if err := p.apply(context.Background(), req); err != nil || req.Header.Get("Authorization") != "Bearer explicit" {
+				t.Errorf("explicit Authorization replaced: %q, %v", req.Header.Get("Authorization"), err)
+			}
+		})
+	}
+
+	if _, err := newAuthProvider(toolkit.AuthConfig{Type: authClientCredentials}, http.DefaultClient); err == nil {
+		t.Error("empty token_url accepted")
+	}
+}
+
+func writeKey(t *testing.T, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaFile := writeKey(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	secretFile := writeKey(t, []byte("shared-secret\n"))
+
+	tests := []struct {
+		name    string
+		ac      toolkit.AuthConfig
+		alg     jwa.SignatureAlgorithm
+		verify  any // key the token must verify with
+		ttl     time.Duration
+		wantErr string
+	}{
+		{
+			name: "rs256 default",
+			ac: toolkit.AuthConfig{
+				KeyFile: rsaFile, KeyID: "k1", Issuer: "synrax", Subject: "tester", Audience: []string{"api"},
+				Claims: map[string]any{"role": "admin"},
+			},
+			alg: jwa.RS256, verify: &rsaKey.PublicKey, ttl: 300 * time.Second,
+		},
+		{
+			name: "hs256 secret trimmed",
+			ac:   toolkit.AuthConfig{KeyFile: secretFile, Algorithm: "HS256", TTLSeconds: 60},
+			alg:  jwa.HS256, verify: []byte("shared-secret"), ttl: time.Minute,
+		},
+		{name: "unknown algorithm", ac: toolkit.AuthConfig{KeyFile: secretFile, Algorithm: "XX999"}, wantErr: "jwt:"},
+		{name: "missing key file", ac: toolkit.AuthConfig{KeyFile: filepath.Join(t.TempDir(), "none")}, wantErr: "read key file"},
+		{name: "not a pem key", ac: toolkit.AuthConfig{KeyFile: secretFile}, wantErr: "parse key file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ac.Type = authJWT
+			p, err := newAuthProvider(tt.ac, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			if err := p.apply(context.Background(), req); err != nil {
+				t.Fatal(err)
+			}
+			signed := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+			msg, err := jws.Parse([]byte(signed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := msg.Signatures()[0].ProtectedHeaders()
+			if headers.Algorithm() != tt.alg || headers.KeyID() != tt.ac.KeyID {
+				t.Errorf("alg %v kid %q, want %v %q", headers.Algorithm(), headers.KeyID(), tt.alg, tt.ac.KeyID)
+			}
+			tok, err := jwt.Parse([]byte(signed), jwt.WithKey(tt.alg, tt.verify))
+			if err != nil {
+				t.Fatalf("token does not verify: %v", err)
+			}
+			if tok.Issuer() != tt.ac.Issuer || tok.Subject() != tt.ac.Subject || !slices.Equal(tok.Audience(), tt.ac.Audience) {
+				t.Errorf("iss %q sub %q aud %v", tok.Issuer(), tok.Subject(), tok.Audience())
+			}
+			if got := tok.Expiration().Sub(tok.IssuedAt()); got != tt.ttl {
+				t.Errorf("lifetime %s, want %s", got, tt.ttl)
+			}
+			for k, v := range tt.ac.Claims {
+				if got, _ := tok.Get(k); got != v {
+					t.Errorf("claim %s = %v, want %v", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyAuth(t *testing.T) {
+	token := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	cfg := toolkit.UnittestConfig{
+		AuthToken: "suite",
+		Identities: map[string]toolkit.Identity{
+			"broken": {Auth: &toolkit.AuthConfig{Type: "kerberos"}},
+			"oauth":  {Auth: oauthConfig(token.URL)},
+			"key":    {Auth: &toolkit.AuthConfig{Type: authAPIKey, APIKey: "k", In: "query", Name: "key"}},
+		},
+	}
+	tests := []struct {
+		tc          toolkit.Test
+		wantHeader  string
+		wantQuery   string
+		wantAuthErr bool
+		wantErr     string
+	}{
+		{tc: toolkit.Test{ID: "list"}, wantHeader: "Bearer suite"},
+		{tc: toolkit.Test{ID: "missing-auth"}},
+		{tc: toolkit.Test{ID: "list", As: "Anonymous"}},
+		{tc: toolkit.Test{ID: "list", As: "key"}, wantQuery: "key=k"},
+		{tc: toolkit.Test{ID: "list", As: "broken"}, wantAuthErr: true, wantErr: `unknown auth type "kerberos"`},
+		{tc: toolkit.Test{ID: "list", As: "oauth"}, wantAuthErr: true, wantErr: "status=500"},
+		{tc: toolkit.Test{ID: "list", As: "nobody"}, wantErr: `unknown identity "nobody"`},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s as %q", tt.tc.ID, tt.tc.As)
+		st := &runState{cfg: cfg, auth: newAuthCache(http.DefaultClient)}
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		err := applyAuth(context.Background(), st, req, tt.tc)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || errors.Is(err, errAuth) != tt.wantAuthErr {
+				t.Errorf("%s: error %v, want %q (errAuth %v)", name, err, tt.wantErr, tt.wantAuthErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := req.Header.Get("Authorization"); got != tt.wantHeader {
+			t.Errorf("%s: Authorization %q, want %q", name, got, tt.wantHeader)
+		}
+		if got := req.URL.RawQuery; got != tt.wantQuery {
+			t.Errorf("%s: query %q, want %q", name, got, tt.wantQuery)
+		}
+	}
+}
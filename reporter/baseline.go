@@ -0,0 +1,129 @@
+package reporter
+
+import (
+	"log/slog"
+	"strings"
+
+	"synrax/toolkit"
+)
+
+// Baseline is a previous run the current run is compared against.
+type Baseline struct {
+	Report toolkit.UnittestReport
+	Source string // report file or "storage"
+}
+
+// LoadBaseline reads a previous report.json as a baseline.
+func LoadBaseline(path string) (*Baseline, error) {
+	report, err := readReport(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Baseline{Report: report, Source: path}, nil
+}
+
+// compareBaseline classifies every case of current against the baseline, in
+// the order of current followed by the removed tests in baseline order. A
+// case missing from a filtered baseline may well predate it, so it is
+// unknown rather than new.
+func compareBaseline(current toolkit.UnittestReport, base *Baseline) *toolkit.BaselineComparison {
+	cmp := &toolkit.BaselineComparison{Source: base.Source}
+	previous := make(map[string]toolkit.UnittestCaseResult, len(base.Report.Results))
+	for _, r := range base.Report.Results {
+		previous[resultRef(r).key()] = r
+	}
+	seen := make(map[string]bool, len(current.Results))
+
+	for _, r := range current.Results {
+		key := resultRef(r).key()
+		seen[key] = true
+		outcome := resultOutcome(r)
+		if outcome == toolkit.OutcomeSkipped {
+			continue // not executed, nothing to compare
+		}
+		failing := isFailing(outcome)
+
+		c := toolkit.BaselineCase{Endpoint: r.Endpoint, Method: r.Method, TestID: r.TestID, Outcome: outcome}
+		old, ok := previous[key]
+		switch {
+		case !ok && base.Report.Filtered:
+			c.Change = toolkit.ChangeUnknown
+			cmp.Unknown++
+		case !ok:
+			c.Change = toolkit.ChangeNewTest
+			cmp.NewTests++
+			if failing {
+				cmp.NewTestFailures++
+			}
+		default:
+			c.BaselineOutcome = resultOutcome(old)
+			wasFailing := isFailing(c.BaselineOutcome)
+			switch {
+			case failing && wasFailing:
+				c.Change = toolkit.ChangeStillFailing
+				cmp.StillFailing++
+			case failing:
+				// a case skipped in the baseline is treated as passing there
+				c.Change = toolkit.ChangeNewFailure
+				cmp.NewFailures++
+			case wasFailing:
+				c.Change = toolkit.ChangeFixed
+				cmp.Fixed++
+			default:
+				continue
+			}
+		}
+		cmp.Cases = append(cmp.Cases, c)
+	}
+
+	for _, old := range base.Report.Results {
+		if seen[resultRef(old).key()] || inSpec(current.Spec, resultRef(old)) {
+			continue // cases the filter deselected are still part of the suite
+		}
+		cmp.RemovedTests++
+		cmp.Cases = append(cmp.Cases, toolkit.BaselineCase{
+			Endpoint:        old.Endpoint,
+			Method:          old.Method,
+			TestID:          old.TestID,
+			Change:          toolkit.ChangeRemovedTest,
+			BaselineOutcome: resultOutcome(old),
+		})
+	}
+
+	slog.Info("runner.baseline: compared", "source", cmp.Source, "new_failures", cmp.NewFailures, "fixed", cmp.Fixed, "still_failing", cmp.StillFailing, "new_tests", cmp.NewTests, "new_test_failures", cmp.NewTestFailures, "removed_tests", cmp.RemovedTests, "unknown", cmp.Unknown)
+	return cmp
+}
+
+// inSpec reports whether the spec still defines the case. Authz matrix cases
+// are derived, they exist as long as their endpoint does.
+func inSpec(spec *toolkit.TestSpec, ref CaseRef) bool {
+	if spec == nil {
+		return false
+	}
+	for _, ep := range spec.Endpoints {
+		if ep.Name != ref.Endpoint || !strings.EqualFold(ep.Method, ref.Method) {
+			continue
+		}
+		if strings.HasPrefix(ref.TestID, "authz-as-") {
+			return true
+		}
+		for _, tc := range ep.Tests {
+			if tc.ID == ref.TestID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resultOutcome tolerates reports written before outcomes existed.
+func resultOutcome(r toolkit.UnittestCaseResult) string {
+	if r.Outcome != "" {
+		return r.Outcome
+	}
+	return caseOutcome(r)
+}
+
+func isFailing(outcome string) bool {
+	return outcome == toolkit.OutcomeFailed || outcome == toolkit.OutcomeErrored
+}
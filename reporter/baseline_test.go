@@ -0,0 +1,76 @@
+package reporter
+
+import (
+	"slices"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func caseResult(id string, outcome string) toolkit.UnittestCaseResult {
+	return toolkit.UnittestCaseResult{Endpoint: "/items", Method: "GET", TestID: id, Outcome: outcome}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	const (
+		passed  = toolkit.OutcomePassed
+		failed  = toolkit.OutcomeFailed
+		errored = toolkit.OutcomeErrored
+		skipped = toolkit.OutcomeSkipped
+		flaky   = toolkit.OutcomeFlaky
+	)
+	// the spec defines a and b; x was removed from it
+	spec := &toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{
+		Name: "/items", Method: "GET", Tests: []toolkit.Test{{ID: "a"}, {ID: "b"}},
+	}}}
+	tests := []struct {
+		name         string
+		base         []toolkit.UnittestCaseResult
+		baseFiltered bool
+		current      []toolkit.UnittestCaseResult
+		want         []string // changes in order
+		regressions  int
+	}{
+		{"unchanged pass", []toolkit.UnittestCaseResult{caseResult("a", passed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", passed)}, nil, 0},
+		{"new failure", []toolkit.UnittestCaseResult{caseResult("a", passed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", failed)}, []string{toolkit.ChangeNewFailure}, 1},
+		{"errored is failing", []toolkit.UnittestCaseResult{caseResult("a", flaky)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", errored)}, []string{toolkit.ChangeNewFailure}, 1},
+		{"fixed", []toolkit.UnittestCaseResult{caseResult("a", failed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", passed)}, []string{toolkit.ChangeFixed}, 0},
+		{"still failing", []toolkit.UnittestCaseResult{caseResult("a", errored)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", failed)}, []string{toolkit.ChangeStillFailing}, 0},
+		{"skipped now", []toolkit.UnittestCaseResult{caseResult("a", failed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", skipped)}, nil, 0},
+		{"skipped in baseline", []toolkit.UnittestCaseResult{caseResult("a", skipped)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", failed)}, []string{toolkit.ChangeNewFailure}, 1},
+		{"new test failing", nil, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", failed)}, []string{toolkit.ChangeNewTest}, 1},
+		{"new test passing", nil, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", passed)}, []string{toolkit.ChangeNewTest}, 0},
+		{"missing from filtered baseline", []toolkit.UnittestCaseResult{caseResult("b", passed)}, true,
+			[]toolkit.UnittestCaseResult{caseResult("a", failed), caseResult("b", failed)},
+			[]string{toolkit.ChangeUnknown, toolkit.ChangeNewFailure}, 1},
+		{"removed test", []toolkit.UnittestCaseResult{caseResult("x", failed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", passed)}, []string{toolkit.ChangeNewTest, toolkit.ChangeRemovedTest}, 0},
+		{"deselected, not removed", []toolkit.UnittestCaseResult{caseResult("a", passed), caseResult("b", failed)}, false,
+			[]toolkit.UnittestCaseResult{caseResult("a", passed)}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &Baseline{Report: toolkit.UnittestReport{Results: tt.base, Filtered: tt.baseFiltered}, Source: "test"}
+			cmp := compareBaseline(toolkit.UnittestReport{Results: tt.current, Spec: spec}, base)
+			var got []string
+			for _, c := range cmp.Cases {
+				got = append(got, c.Change)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changes %v, want %v", got, tt.want)
+			}
+			if r := cmp.Regressions(); r != tt.regressions {
+				t.Errorf("regressions %d, want %d", r, tt.regressions)
+			}
+		})
+	}
+}
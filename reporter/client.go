@@ -0,0 +1,174 @@
+package reporter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"synrax/toolkit"
+)
+
+// newTargetClient builds the http.Client used for every request against the
+// service under test.
+func newTargetClient(cfg toolkit.UnittestConfig) (*http.Client, error) {
+	transport, dialer, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.TrimSpace(cfg.UnixSocket) != "":
+		socket := strings.TrimSpace(cfg.UnixSocket)
+		slog.Info("tester.client: dialing unix socket", "path", socket)
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	case len(cfg.HostOverrides) > 0:
+		overrides := cfg.HostOverrides
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, overrideAddr(overrides, addr))
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newAuthClient builds the http.Client for OAuth2 token requests. It shares
+// TLS and proxy settings with the target client, but not the unix socket and
+// host overrides: those route the service under test, not the token URL.
+func newAuthClient(cfg toolkit.UnittestConfig) (*http.Client, error) {
+	transport, _, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+func newTransport(cfg toolkit.UnittestConfig) (*http.Transport, *net.Dialer, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.TLS != nil {
+		tlsCfg, err := buildTLSConfig(*cfg.TLS)
+		if err != nil {
+			return nil, nil, err
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	if strings.TrimSpace(cfg.Proxy) != "" {
+		proxyURL, err := url.Parse(strings.TrimSpace(cfg.Proxy))
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, nil, fmt.Errorf("proxy must be an absolute URL, got=%q", cfg.Proxy)
+		}
+		slog.Info("tester.client: routing through proxy", "proxy", proxyURL.Redacted())
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	// connect and TLS budgets are enforced per request, see withTimeouts
+	transport.TLSHandshakeTimeout = 0
+	dialer := &net.Dialer{KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	return transport, dialer, nil
+}
+
+func buildTLSConfig(tc toolkit.TLSConfig) (*tls.Config, error) {
+	out := &tls.Config{ServerName: tc.ServerName}
+
+	if tc.InsecureSkipVerify {
+		slog.Warn("tester.client: TLS certificate verification disabled by config")
+		out.InsecureSkipVerify = true
+	}
+
+	if tc.CAFile != "" {
+		pem, err := os.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: ca_file %q contains no PEM certificates", tc.CAFile)
+		}
+		out.RootCAs = pool
+	}
+
+	if tc.CertFile != "" || tc.KeyFile != "" {
+		if tc.CertFile == "" || tc.KeyFile == "" {
+			return nil, fmt.Errorf("tls: cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: load client certificate: %w", err)
+		}
+		out.Certificates = []tls.Certificate{cert}
+	}
+
+	switch strings.TrimSpace(tc.MinVersion) {
+	case "":
+	case "1.0":
+		out.MinVersion = tls.VersionTLS10
+	case "1.1":
+		out.MinVersion = tls.VersionTLS11
+	case "1.2":
+		out.MinVersion = tls.VersionTLS12
+	case "1.3":
+		out.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("tls: unsupported min_version %q", tc.MinVersion)
+	}
+
+	return out, nil
+}
+
+// overrideAddr maps a dial address through the hosts-style override table.
+// "host:port" entries win over bare "host" entries; a target without a port
+// keeps the original one.
+func overrideAddr(overrides map[string]string, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	target, ok := overrides[addr]
+	if !ok {
+		if target, ok = overrides[host]; !ok {
+			return addr
+		}
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, port)
+	}
+	slog.Debug("tester.client: host override", "addr", addr, "target", target)
+	return target
+}
+
+// isTLSError reports whether err comes from a failed TLS handshake or
+// certificate verification rather than from the network.
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		alertErr     tls.AlertError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) ||
+		// alerts sent by the peer ("remote error: tls: bad certificate") use an unexported type
+		strings.Contains(err.Error(), "tls: ")
+}
@@ -0,0 +1,111 @@
+package reporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"synrax/toolkit"
+)
+
+func TestOverrideAddr(t *testing.T) {
+	overrides := map[string]string{
+		"api.test":      "127.0.0.1",
+		"api.test:8443": "10.0.0.2:9443",
+		"other.test":    "10.0.0.3:81",
+	}
+	tests := []struct {
+		addr, want string
+	}{
+		{"api.test:443", "127.0.0.1:443"},
+		{"api.test:8443", "10.0.0.2:9443"},
+		{"other.test:80", "10.0.0.3:81"},
+		{"unknown.test:80", "unknown.test:80"},
+		{"no-port", "no-port"},
+	}
+	for _, tt := range tests {
+		if got := overrideAddr(overrides, tt.addr); got != tt.want {
+			t.Errorf("overrideAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func tokenServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func oauthConfig(tokenURL string) *toolkit.AuthConfig {
+	return &toolkit.AuthConfig{Type: authClientCredentials, TokenURL: tokenURL, ClientID: "id", ClientSecret: "secret"}
+}
+
+func TestOAuth2TokenBypassesUnixSocket(t *testing.T) {
+	token := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+	})
+
+	// short path: unix socket names are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "sx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "t.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotAuth string
+	target := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	})}
+	go target.Serve(ln)
+	t.Cleanup(func() { target.Close() })
+
+	cfg := toolkit.UnittestConfig{BaseURL: "http://svc.internal", UnixSocket: socket, Auth: oauthConfig(token.URL)}
+	spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{Name: "/items", Method: "GET", Tests: []toolkit.Test{{ID: "list"}}}}}
+	rep, err := Run(context.Background(), spec, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := rep.Results[0]; res.Outcome != toolkit.OutcomePassed {
+		t.Fatalf("outcome %s: %s %s", res.Outcome, res.Failure, res.Error)
+	}
+	if gotAuth != "Bearer tok" {
+		t.Errorf("target saw Authorization %q", gotAuth)
+	}
+}
+
+func TestOAuth2TokenFetchStopsOnCancel(t *testing.T) {
+	release := make(chan struct{})
+	token := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	t.Cleanup(func() { close(release) }) // runs before the server closes
+	target := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	cfg := toolkit.UnittestConfig{BaseURL: target.URL, Auth: oauthConfig(token.URL)}
+	spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{Name: "/items", Method: "GET", Tests: []toolkit.Test{{ID: "list"}}}}}
+
+	start := time.Now()
+	if _, err := Run(ctx, spec, cfg, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("run took %s after cancel, token fetch ignored the run context", elapsed)
+	}
+}
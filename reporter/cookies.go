@@ -0,0 +1,139 @@
+package reporter
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+
+	"synrax/toolkit"
+)
+
+const (
+	cookieJarSuite    = "suite"
+	cookieJarIdentity = "identity"
+)
+
+// cookieJars hands out clients that share the base transport but carry the
+// jar of their suite or identity, so session cookies survive between tests.
+type cookieJars struct {
+	mu      sync.Mutex
+	mode    string
+	base    *http.Client
+	jars    map[string]http.CookieJar
+	clients map[string]*http.Client
+}
+
+func newCookieJars(mode string, base *http.Client) *cookieJars {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "", cookieJarSuite, cookieJarIdentity:
+	default:
+		slog.Warn("tester.cookies: unknown cookie_jar mode, cookies disabled", "mode", mode)
+		mode = ""
+	}
+	return &cookieJars{
+		mode:    mode,
+		base:    base,
+		jars:    make(map[string]http.CookieJar),
+		clients: make(map[string]*http.Client),
+	}
+}
+
+func (c *cookieJars) key(identity string) string {
+	if c.mode == cookieJarIdentity {
+		return identity
+	}
+	return ""
+}
+
+// client returns the http.Client for an identity; the base client when jars are off.
+func (c *cookieJars) client(identity string) *http.Client {
+	if c.mode == "" {
+		return c.base
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := c.key(identity)
+	if cl, ok := c.clients[key]; ok {
+		return cl
+	}
+	jar, _ := cookiejar.New(nil) // only fails on a bad PublicSuffixList option
+	cl := *c.base
+	cl.Jar = jar
+	c.jars[key] = jar
+	c.clients[key] = &cl
+	return &cl
+}
+
+// applyCSRF copies the configured CSRF cookie into its header on unsafe methods.
+func applyCSRF(st *runState, cl *http.Client, req *http.Request, tc toolkit.Test) {
+	csrf := st.cfg.CSRF
+	if csrf == nil || cl.Jar == nil || csrf.Cookie == "" || csrf.Header == "" {
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	id := strings.ToLower(tc.ID)
+	if strings.Contains(id, "missing-csrf") || strings.Contains(id, "missing_csrf") {
+		return
+	}
+	if req.Header.Get(csrf.Header) != "" {
+		return
+	}
+	for _, ck := range cl.Jar.Cookies(req.URL) {
+		if ck.Name == csrf.Cookie {
+			req.Header.Set(csrf.Header, ck.Value)
+			return
+		}
+	}
+}
+
+// cookieMismatch returns a description of the first Set-Cookie expectation
+// that the response header does not satisfy.
+func cookieMismatch(expected map[string]toolkit.CookieExpectation, header http.Header) (string, bool) {
+	set := make(map[string]*http.Cookie)
+	for _, ck := range (&http.Response{Header: header}).Cookies() {
+		set[ck.Name] = ck
+	}
+	for name, exp := range expected {
+		ck, ok := set[name]
+		if !ok {
+			return fmt.Sprintf("cookie %s was not set", name), true
+		}
+		if exp.Value == "..." {
+			if strings.TrimSpace(ck.Value) == "" {
+				return fmt.Sprintf("cookie %s has an empty value", name), true
+			}
+		} else if exp.Value != "" && ck.Value != exp.Value {
+			return fmt.Sprintf("cookie %s value mismatch (expected=%q got=%q)", name, exp.Value, ck.Value), true
+		}
+		if exp.HttpOnly != nil && ck.HttpOnly != *exp.HttpOnly {
+			return fmt.Sprintf("cookie %s HttpOnly mismatch (expected=%t got=%t)", name, *exp.HttpOnly, ck.HttpOnly), true
+		}
+		if exp.Secure != nil && ck.Secure != *exp.Secure {
+			return fmt.Sprintf("cookie %s Secure mismatch (expected=%t got=%t)", name, *exp.Secure, ck.Secure), true
+		}
+		if exp.SameSite != "" && !strings.EqualFold(sameSiteName(ck.SameSite), exp.SameSite) {
+			return fmt.Sprintf("cookie %s SameSite mismatch (expected=%s got=%s)", name, exp.SameSite, sameSiteName(ck.SameSite)), true
+		}
+	}
+	return "", false
+}
+
+func sameSiteName(s http.SameSite) string {
+	switch s {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,181 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func TestCookieJars(t *testing.T) {
+	base := &http.Client{}
+	tests := []struct {
+		mode       string
+		wantJar    bool
+		wantShared bool // admin and user share a jar
+	}{
+		{mode: ""},
+		{mode: "per-test"}, // unknown modes turn cookies off
+		{mode: "suite", wantJar: true, wantShared: true},
+		{mode: " Identity ", wantJar: true},
+	}
+	for _, tt := range tests {
+		jars := newCookieJars(tt.mode, base)
+		admin, user := jars.client("admin"), jars.client("user")
+		if !tt.wantJar {
+			if admin != base || user != base {
+				t.Errorf("mode %q: clients are not the base client", tt.mode)
+			}
+			continue
+		}
+		if admin.Jar == nil || admin == base {
+			t.Fatalf("mode %q: no jar", tt.mode)
+		}
+		if jars.client("admin") != admin {
+			t.Errorf("mode %q: a second client for the same identity", tt.mode)
+		}
+
+		u, _ := url.Parse("http://api.test/")
+		admin.Jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "admin"}})
+		shared := len(user.Jar.Cookies(u)) > 0
+		if shared != tt.wantShared {
+			t.Errorf("mode %q: user sees the admin session %v, want %v", tt.mode, shared, tt.wantShared)
+		}
+	}
+}
+
+func TestApplyCSRF(t *testing.T) {
+	csrf := &toolkit.CSRFConfig{Cookie: "csrftoken", Header: "X-CSRF-Token"}
+	tests := []struct {
+		name   string
+		csrf   *toolkit.CSRFConfig
+		noJar  bool
+		method string
+		id     string
+		preset string // header value the test sets itself
+		cookie string // csrftoken in the jar
+		want   string
+	}{
+		{name: "post", csrf: csrf, method: http.MethodPost, id: "create", cookie: "tok", want: "tok"},
+		{name: "delete", csrf: csrf, method: http.MethodDelete, id: "remove", cookie: "tok", want: "tok"},
+		{name: "safe method", csrf: csrf, method: http.MethodGet, id: "list", cookie: "tok"},
+		{name: "no cookie yet", csrf: csrf, method: http.MethodPost, id: "create"},
+		{name: "missing csrf test", csrf: csrf, method: http.MethodPost, id: "create-Missing-CSRF", cookie: "tok"},
+		{name: "explicit header kept", csrf: csrf, method: http.MethodPut, id: "update", preset: "mine", cookie: "tok", want: "mine"},
+		{name: "no config", method: http.MethodPost, id: "create", cookie: "tok"},
+		{name: "incomplete config", csrf: &toolkit.CSRFConfig{Cookie: "csrftoken"}, method: http.MethodPost, id: "create", cookie: "tok"},
+		{name: "no jar", csrf: csrf, noJar: true, method: http.MethodPost, id: "create", cookie: "tok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &runState{cfg: toolkit.UnittestConfig{CSRF: tt.csrf}}
+			jars := newCookieJars(cookieJarSuite, &http.Client{})
+			if tt.noJar {
+				jars = newCookieJars("", &http.Client{})
+			}
+			cl := jars.client("")
+			req := httptest.NewRequest(tt.method, "http://api.test/items", nil)
+			if tt.cookie != "" && cl.Jar != nil {
+				cl.Jar.SetCookies(req.URL, []*http.Cookie{{Name: "session", Value: "s"}, {Name: "csrftoken", Value: tt.cookie}})
+			}
+			if tt.preset != "" {
+				req.Header.Set("X-CSRF-Token", tt.preset)
+			}
+			applyCSRF(st, cl, req, toolkit.Test{ID: tt.id})
+			if got := req.Header.Get("X-CSRF-Token"); got != tt.want {
+				t.Errorf("X-CSRF-Token %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieMismatch(t *testing.T) {
+	yes, no := true, false
+	header := http.Header{"Set-Cookie": {
+		"session=abc; HttpOnly; Secure; SameSite=Strict",
+		"theme=dark; SameSite=Lax",
+		"empty=",
+	}}
+	tests := []struct {
+		name     string
+		expected map[string]toolkit.CookieExpectation
+		want     string // "" when the header satisfies it
+	}{
+		{"none expected", nil, ""},
+		{"all attributes", map[string]toolkit.CookieExpectation{"session": {Value: "abc", HttpOnly: &yes, Secure: &yes, SameSite: "strict"}}, ""},
+		{"any value", map[string]toolkit.CookieExpectation{"session": {Value: "..."}}, ""},
+		{"not set", map[string]toolkit.CookieExpectation{"token": {}}, "cookie token was not set"},
+		{"empty value", map[string]toolkit.CookieExpectation{"empty": {Value: "..."}}, "cookie empty has an empty value"},
+		{"value", map[string]toolkit.CookieExpectation{"theme": {Value: "light"}}, `cookie theme value mismatch (expected="light" got="dark")`},
+		{"http only", map[string]toolkit.CookieExpectation{"theme": {HttpOnly: &yes}}, "cookie theme HttpOnly mismatch (expected=true got=false)"},
+		{"not secure", map[string]toolkit.CookieExpectation{"session": {Secure: &no}}, "cookie session Secure mismatch (expected=false got=true)"},
+		{"same site", map[string]toolkit.CookieExpectation{"theme": {SameSite: "Strict"}}, "cookie theme SameSite mismatch (expected=Strict got=Lax)"},
+		{"same site unset", map[string]toolkit.CookieExpectation{"empty": {SameSite: "None"}}, "cookie empty SameSite mismatch (expected=None got=)"},
+	}
+	for _, tt := range tests {
+		got, mismatch := cookieMismatch(tt.expected, header)
+		if got != tt.want || mismatch != (tt.want != "") {
+			t.Errorf("%s: got %q %v, want %q", tt.name, got, mismatch, tt.want)
+		}
+	}
+}
+
+// sessionServer logs in whoever sends a bearer token and accepts a POST only
+// with that identity's session cookie and the CSRF token copied from its
+// cookie.
+func sessionServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: token, Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "csrf-" + token, Path: "/"})
+		case "/items":
+			session, err := r.Cookie("session")
+			if err != nil || session.Value != token || r.Header.Get("X-CSRF-Token") != "csrf-"+token {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIdentitySessions(t *testing.T) {
+	srv := sessionServer(t)
+	cfg := toolkit.UnittestConfig{
+		CookieJar: cookieJarIdentity,
+		CSRF:      &toolkit.CSRFConfig{Cookie: "csrftoken", Header: "X-CSRF-Token"},
+		Identities: map[string]toolkit.Identity{
+			"admin": {Token: "admin-token"},
+			"user":  {Token: "user-token"},
+		},
+	}
+	created := []int{http.StatusCreated}
+	spec := toolkit.TestSpec{BaseURL: srv.URL, Endpoints: []toolkit.Endpoint{
+		{Name: "/login", Method: "POST", Tests: []toolkit.Test{{ID: "login-admin", As: "admin"}, {ID: "login-user", As: "user"}}},
+		{Name: "/items", Method: "POST", Tests: []toolkit.Test{
+			{ID: "create-admin", As: "admin", Expectation: toolkit.Expectation{Status: created}},
+			{ID: "create-user", As: "user", Expectation: toolkit.Expectation{Status: created}},
+			{ID: "create-missing-csrf", As: "user", Expectation: toolkit.Expectation{Status: []int{http.StatusForbidden}}},
+		}},
+	}}
+	report, err := Run(context.Background(), spec, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.Results {
+		if !r.Passed {
+			t.Errorf("%s: status %d, %s", r.TestID, r.Status, r.Why)
+		}
+	}
+	if report.Summary.Passed != 5 {
+		t.Errorf("%d passed, want 5", report.Summary.Passed)
+	}
+}
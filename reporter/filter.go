@@ -0,0 +1,150 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"synrax/toolkit"
+)
+
+// Options tune a single run without changing the spec or the repo config.
+type Options struct {
+	Filter   Filter
+	Baseline *Baseline // compared against after the run, nil skips the comparison
+	Output   Output
+	Stream   *Stream // progress events while the run executes, nil disables them
+	Commit   string  // commit SHA recorded on the report
+	RunID    string  // recorded on the report, generated by the caller
+}
+
+// Filter selects the cases a run executes. Empty fields select everything;
+// unselected cases are left out of the report entirely.
+type Filter struct {
+	Tags        []string // keep cases carrying any of these tags
+	ExcludeTags []string // drop cases carrying any of these tags
+	Endpoint    string   // glob on the endpoint name, `*` spans path segments
+	Methods     []string
+	TestID      string // regular expression on the test id
+	Cases       []CaseRef
+}
+
+// CaseRef names one case exactly; when Filter.Cases is set only those run.
+type CaseRef struct {
+	Endpoint string
+	Method   string
+	TestID   string
+}
+
+func (c CaseRef) key() string {
+	return strings.ToUpper(c.Method) + " " + c.Endpoint + " " + c.TestID
+}
+
+// Validate reports malformed patterns before any test runs.
+func (f Filter) Validate() error {
+	_, err := f.compile()
+	return err
+}
+
+type selector struct {
+	tags        map[string]bool
+	excludeTags map[string]bool
+	endpoint    *regexp.Regexp
+	methods     map[string]bool
+	testID      *regexp.Regexp
+	cases       map[string]bool
+}
+
+func (f Filter) compile() (*selector, error) {
+	sel := &selector{
+		tags:        lowerSet(f.Tags),
+		excludeTags: lowerSet(f.ExcludeTags),
+		methods:     make(map[string]bool),
+	}
+	for _, m := range f.Methods {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			sel.methods[m] = true
+		}
+	}
+	if glob := strings.TrimSpace(f.Endpoint); glob != "" {
+		pattern := regexp.QuoteMeta(glob)
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			return nil, fmt.Errorf("endpoint filter %q: %w", glob, err)
+		}
+		sel.endpoint = re
+	}
+	if expr := strings.TrimSpace(f.TestID); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("test id filter %q: %w", expr, err)
+		}
+		sel.testID = re
+	}
+	if len(f.Cases) > 0 {
+		sel.cases = make(map[string]bool, len(f.Cases))
+		for _, c := range f.Cases {
+			sel.cases[c.key()] = true
+		}
+	}
+	return sel, nil
+}
+
+// all reports whether the selector keeps every case of the spec.
+func (s *selector) all() bool {
+	return len(s.tags) == 0 && len(s.excludeTags) == 0 && s.endpoint == nil &&
+		len(s.methods) == 0 && s.testID == nil && s.cases == nil
+}
+
+// matchesEndpoint applies the filters that do not depend on the test.
+func (s *selector) matchesEndpoint(ep toolkit.Endpoint) bool {
+	if s.endpoint != nil && !s.endpoint.MatchString(ep.Name) {
+		return false
+	}
+	if len(s.methods) > 0 && !s.methods[strings.ToUpper(ep.Method)] {
+		return false
+	}
+	return true
+}
+
+// matches reports whether a test is selected. Tags are inherited from the endpoint.
+func (s *selector) matches(ep toolkit.Endpoint, tc toolkit.Test) bool {
+	if !s.matchesEndpoint(ep) {
+		return false
+	}
+	if s.testID != nil && !s.testID.MatchString(tc.ID) {
+		return false
+	}
+	if s.cases != nil && !s.cases[CaseRef{Endpoint: ep.Name, Method: ep.Method, TestID: tc.ID}.key()] {
+		return false
+	}
+	tags := append(append([]string(nil), ep.Tags...), tc.Tags...)
+	if len(s.tags) > 0 && !anyIn(tags, s.tags) {
+		return false
+	}
+	if anyIn(tags, s.excludeTags) {
+		return false
+	}
+	return true
+}
+
+func lowerSet(values []string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out[v] = true
+		}
+	}
+	return out
+}
+
+func anyIn(values []string, set map[string]bool) bool {
+	for _, v := range values {
+		if set[strings.ToLower(strings.TrimSpace(v))] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package reporter
+
+import (
+	"context"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{"empty", Filter{}, false},
+		{"glob with regexp characters", Filter{Endpoint: "/items/(id)+[x]"}, false},
+		{"test id regexp", Filter{TestID: "^auth-.*$"}, false},
+		{"bad test id regexp", Filter{TestID: "auth-("}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	items := toolkit.Endpoint{Name: "/v1/items/{id}", Method: "get", Tags: []string{"Smoke"}}
+	users := toolkit.Endpoint{Name: "/v1/users", Method: "POST"}
+	list := toolkit.Test{ID: "list-items", Tags: []string{"slow"}}
+	create := toolkit.Test{ID: "create-user"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		ep     toolkit.Endpoint
+		tc     toolkit.Test
+		want   bool
+	}{
+		{"empty keeps all", Filter{}, items, list, true},
+		{"glob spans segments", Filter{Endpoint: "/v1/*"}, items, list, true},
+		{"glob anchored", Filter{Endpoint: "/items/*"}, items, list, false},
+		{"question mark", Filter{Endpoint: "/v?/users"}, users, create, true},
+		{"glob literal braces", Filter{Endpoint: "/v1/items/{id}"}, items, list, true},
+		{"method case", Filter{Methods: []string{" GET "}}, items, list, true},
+		{"method excluded", Filter{Methods: []string{"get"}}, users, create, false},
+		{"test id regexp", Filter{TestID: "^list-"}, items, list, true},
+		{"test id no match", Filter{TestID: "^create"}, items, list, false},
+		{"tag inherited from endpoint", Filter{Tags: []string{"smoke"}}, items, list, true},
+		{"tag on test", Filter{Tags: []string{"SLOW"}}, items, list, true},
+		{"tag missing", Filter{Tags: []string{"smoke"}}, users, create, false},
+		{"exclude tag", Filter{ExcludeTags: []string{"slow"}}, items, list, false},
+		{"exclude wins over tag", Filter{Tags: []string{"smoke"}, ExcludeTags: []string{"slow"}}, items, list, false},
+		{"exact case", Filter{Cases: []CaseRef{{Endpoint: "/v1/users", Method: "post", TestID: "create-user"}}}, users, create, true},
+		{"exact case, other test", Filter{Cases: []CaseRef{{Endpoint: "/v1/users", Method: "POST", TestID: "other"}}}, users, create, false},
+		{"all fields", Filter{Endpoint: "/v1/*", Methods: []string{"GET"}, TestID: "list", Tags: []string{"smoke"}}, items, list, true},
+	}
+	for _, tt := range tests {
+		sel, err := tt.filter.compile()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := sel.matches(tt.ep, tt.tc); got != tt.want {
+			t.Errorf("%s: matches %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorAll(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Tags: []string{" "}, Methods: []string{""}, Endpoint: " "}, true},
+		{Filter{Tags: []string{"smoke"}}, false},
+		{Filter{ExcludeTags: []string{"slow"}}, false},
+		{Filter{TestID: "x"}, false},
+		{Filter{Cases: []CaseRef{{Endpoint: "/a", Method: "GET", TestID: "x"}}}, false},
+	}
+	for _, tt := range tests {
+		sel, err := tt.filter.compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sel.all(); got != tt.want {
+			t.Errorf("%+v: all %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestRunRecordsFilter(t *testing.T) {
+	srv := limitedServer(t, 0)
+	spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{
+		Name: "/items", Method: "GET", Tests: []toolkit.Test{{ID: "list"}, {ID: "get", Tags: []string{"smoke"}}},
+	}}}
+	for _, filter := range []Filter{{}, {Tags: []string{"smoke"}}} {
+		rep, err := Run(context.Background(), spec, toolkit.UnittestConfig{BaseURL: srv.URL}, Options{Filter: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := len(filter.Tags) > 0; rep.Filtered != want {
+			t.Errorf("filter %+v: report filtered %v, want %v", filter, rep.Filtered, want)
+		}
+		if want := 2 - len(filter.Tags); len(rep.Results) != want {
+			t.Errorf("filter %+v: %d results, want %d", filter, len(rep.Results), want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"synrax/toolkit"
+)
+
+const (
+	hookBeforeAll  = "before_all"
+	hookAfterAll   = "after_all"
+	hookBeforeEach = "before_each"
+	hookAfterEach  = "after_each"
+
+	hookScopeSuite = "suite"
+)
+
+// runHooks executes every hook of one stage in order. Hook failures are
+// reported but never stop the remaining hooks, so teardown always completes.
+func runHooks(ctx context.Context, st *runState, stage string, scope string, hooks []toolkit.Hook, testID string) []toolkit.UnittestHookResult {
+	results := make([]toolkit.UnittestHookResult, 0, len(hooks))
+	for _, h := range hooks {
+		res := runHook(ctx, st, stage, scope, h, testID)
+		if !res.Passed {
+			slog.WarnContext(ctx, "tester.hook: failed", "stage", stage, "scope", scope, "hook_id", h.ID, "failure", res.Failure, "error", res.Error)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func runHook(ctx context.Context, st *runState, stage string, scope string, h toolkit.Hook, testID string) toolkit.UnittestHookResult {
+	ctx = toolkit.WithLogAttrs(ctx, slog.String("hook_id", h.ID))
+	hr := toolkit.UnittestHookResult{
+		Stage:    stage,
+		Scope:    scope,
+		HookID:   h.ID,
+		Endpoint: h.Name,
+		Method:   h.Method,
+		TestID:   testID,
+	}
+
+	if _, _, err := resolveAuth(st.cfg, h.As); err != nil {
+		hr.Failure = "request_build_error"
+		hr.Error = err.Error()
+		return hr
+	}
+
+	fullURL, err := buildURL(st.baseURL, h.Name, h.Request.PathParams, h.Request.Query)
+	if err != nil {
+		hr.Failure = "request_build_error"
+		hr.Error = "buildURL: " + err.Error()
+		return hr
+	}
+
+	// hooks reuse the regular request path, so auth and content type injection behave like tests
+	ep := toolkit.Endpoint{Name: h.Name, Method: h.Method}
+	tc := toolkit.Test{ID: h.ID, As: h.As, Request: h.Request, Expectation: h.Expectation}
+	res, runErr := executeRequest(ctx, st, ep, tc, fullURL)
+	hr.Status = res.Status
+	hr.LatencyMS = res.LatencyMS
+	if runErr != nil {
+		hr.Failure, _ = classifyRequestError(runErr)
+		hr.Error = runErr.Error()
+		return hr
+	}
+	if !statusMatches(res.Status, h.Expectation.Status) {
+		hr.Failure = "status_mismatch"
+		hr.Error = fmt.Sprintf("status mismatch (got=%d expected=%v)", res.Status, h.Expectation.Status)
+		return hr
+	}
+
+	hr.Passed = true
+	return hr
+}
+
+func recordHooks(rep *toolkit.UnittestReport, results []toolkit.UnittestHookResult) {
+	for _, res := range results {
+		rep.Hooks = append(rep.Hooks, res)
+		if !res.Passed {
+			rep.Summary.HookFailures++
+		}
+	}
+}
+
+// firstFailedHook returns the first failed hook of a stage, nil when all passed.
+func firstFailedHook(results []toolkit.UnittestHookResult) *toolkit.UnittestHookResult {
+	for i := range results {
+		if !results[i].Passed {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// hookFailedResult is the result of a case whose setup hook failed. The case
+// sent no request, so it is errored rather than failed.
+func hookFailedResult(ep toolkit.Endpoint, tc toolkit.Test, hook toolkit.UnittestHookResult) toolkit.UnittestCaseResult {
+	return toolkit.UnittestCaseResult{
+		Endpoint:        ep.Name,
+		Method:          ep.Method,
+		TestID:          tc.ID,
+		As:              tc.As,
+		Outcome:         toolkit.OutcomeErrored,
+		Failure:         "hook_failed",
+		Why:             fmt.Sprintf("Setup hook %s %q failed, the case was not run.", hook.Stage, hook.HookID),
+		Error:           hook.Error,
+		ExpectedStatus:  append([]int(nil), tc.Expectation.Status...),
+		ExpectedContent: tc.Expectation.Content,
+	}
+}
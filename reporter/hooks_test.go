@@ -0,0 +1,175 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"synrax/toolkit"
+)
+
+// hookServer answers 500 on paths starting with /fail and 200 elsewhere, and
+// records every request path in order.
+func hookServer(t *testing.T, onRequest func(path string)) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if onRequest != nil {
+			onRequest(r.URL.Path)
+		}
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func hooks(paths ...string) []toolkit.Hook {
+	out := make([]toolkit.Hook, len(paths))
+	for i, p := range paths {
+		out[i] = toolkit.Hook{ID: strings.TrimPrefix(p, "/"), Name: p, Method: "POST"}
+	}
+	return out
+}
+
+// hookSpec wraps one endpoint with two tests in hooks at both levels. The
+// override replaces a hook path to make it fail.
+func hookSpec(override map[string]string) toolkit.TestSpec {
+	path := func(p string) []toolkit.Hook {
+		if o, ok := override[p]; ok {
+			p = o
+		}
+		return hooks(p)
+	}
+	return toolkit.TestSpec{
+		Hooks: toolkit.Hooks{BeforeAll: path("/s-ba"), BeforeEach: path("/s-be"), AfterEach: path("/s-ae"), AfterAll: path("/s-aa")},
+		Endpoints: []toolkit.Endpoint{{
+			Name: "/items", Method: "GET",
+			Tests: []toolkit.Test{{ID: "one"}, {ID: "two"}},
+			Hooks: toolkit.Hooks{BeforeAll: path("/e-ba"), BeforeEach: path("/e-be"), AfterEach: path("/e-ae"), AfterAll: path("/e-aa")},
+		}},
+	}
+}
+
+func TestHookOrder(t *testing.T) {
+	srv, seen := hookServer(t, nil)
+	rep, err := Run(context.Background(), hookSpec(nil), toolkit.UnittestConfig{BaseURL: srv.URL}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	each := []string{"/s-be", "/e-be", "/items", "/e-ae", "/s-ae"}
+	want := append([]string{"/s-ba", "/e-ba"}, each...)
+	want = append(append(want, each...), "/e-aa", "/s-aa")
+	if got := seen(); !slices.Equal(got, want) {
+		t.Errorf("requests\n got %v\nwant %v", got, want)
+	}
+	if rep.Summary.Passed != 2 || rep.Summary.HookFailures != 0 {
+		t.Errorf("passed %d, hook failures %d", rep.Summary.Passed, rep.Summary.HookFailures)
+	}
+}
+
+func TestSetupHookFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     string
+		want     []string
+		errored  int
+		passed   int
+		failures int
+	}{
+		{
+			name: "suite before_all", fail: "/s-ba",
+			want:    []string{"/fail-s-ba", "/s-aa"},
+			errored: 2, failures: 1,
+		},
+		{
+			name: "endpoint before_all", fail: "/e-ba",
+			want:    []string{"/s-ba", "/fail-e-ba", "/e-aa", "/s-aa"},
+			errored: 2, failures: 1,
+		},
+		{
+			name: "suite before_each", fail: "/s-be",
+			want: []string{"/s-ba", "/e-ba",
+				"/fail-s-be", "/s-ae",
+				"/fail-s-be", "/s-ae",
+				"/e-aa", "/s-aa"},
+			errored: 2, failures: 2,
+		},
+		{
+			name: "endpoint before_each", fail: "/e-be",
+			want: []string{"/s-ba", "/e-ba",
+				"/s-be", "/fail-e-be", "/e-ae", "/s-ae",
+				"/s-be", "/fail-e-be", "/e-ae", "/s-ae",
+				"/e-aa", "/s-aa"},
+			errored: 2, failures: 2,
+		},
+		{
+			name: "after_each does not error the case", fail: "/e-ae",
+			want: []string{"/s-ba", "/e-ba",
+				"/s-be", "/e-be", "/items", "/fail-e-ae", "/s-ae",
+				"/s-be", "/e-be", "/items", "/fail-e-ae", "/s-ae",
+				"/e-aa", "/s-aa"},
+			passed: 2, failures: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, seen := hookServer(t, nil)
+			spec := hookSpec(map[string]string{tt.fail: "/fail-" + strings.TrimPrefix(tt.fail, "/")})
+			rep, err := Run(context.Background(), spec, toolkit.UnittestConfig{BaseURL: srv.URL}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := seen(); !slices.Equal(got, tt.want) {
+				t.Errorf("requests\n got %v\nwant %v", got, tt.want)
+			}
+			s := rep.Summary
+			if s.Errored != tt.errored || s.Passed != tt.passed || s.Failed != 0 || s.HookFailures != tt.failures {
+				t.Errorf("errored %d passed %d failed %d hook failures %d, want %d %d 0 %d",
+					s.Errored, s.Passed, s.Failed, s.HookFailures, tt.errored, tt.passed, tt.failures)
+			}
+			for _, res := range rep.Results {
+				if tt.errored > 0 && (res.Failure != "hook_failed" || !strings.Contains(res.Why, "fail-")) {
+					t.Errorf("%s: failure %q why %q", res.TestID, res.Failure, res.Why)
+				}
+			}
+		})
+	}
+}
+
+func TestTeardownAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv, seen := hookServer(t, func(path string) {
+		if path == "/items" {
+			cancel()
+			time.Sleep(50 * time.Millisecond) // answers after the client gave up
+		}
+	})
+	rep, err := Run(ctx, hookSpec(nil), toolkit.UnittestConfig{BaseURL: srv.URL}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/s-ba", "/e-ba", "/s-be", "/e-be", "/items", "/e-ae", "/s-ae", "/e-aa", "/s-aa"}
+	if got := seen(); !slices.Equal(got, want) {
+		t.Errorf("requests\n got %v\nwant %v", got, want)
+	}
+	if !rep.Interrupted || rep.Summary.Skipped != 2 {
+		t.Errorf("interrupted %v, skipped %d; want true and 2", rep.Interrupted, rep.Summary.Skipped)
+	}
+}
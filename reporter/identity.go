@@ -0,0 +1,155 @@
+package reporter
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"synrax/toolkit"
+)
+
+const identityAnonymous = "anonymous"
+
+// authz matrix outcomes
+const (
+	authzAllow        = "allow"
+	authzForbidden    = "forbidden"
+	authzUnauthorized = "unauthorized"
+)
+
+// resolveAuth returns the identity name and credentials for a test's `as`
+// selector. An empty selector falls back to DefaultIdentity and then to the
+// suite Auth or legacy AuthToken. A nil AuthConfig means no credentials.
+func resolveAuth(cfg toolkit.UnittestConfig, as string) (string, *toolkit.AuthConfig, error) {
+	name := strings.TrimSpace(as)
+	if name == "" {
+		name = strings.TrimSpace(cfg.DefaultIdentity)
+	}
+	if name == "" {
+		if cfg.Auth != nil {
+			return "", cfg.Auth, nil
+		}
+		if cfg.AuthToken != "" {
+			return "", &toolkit.AuthConfig{Type: authBearer, Token: cfg.AuthToken}, nil
+		}
+		return "", nil, nil
+	}
+	if strings.EqualFold(name, identityAnonymous) {
+		return identityAnonymous, nil, nil
+	}
+	id, ok := cfg.Identities[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown identity %q", name)
+	}
+	if id.Auth != nil {
+		return name, id.Auth, nil
+	}
+	if id.Token != "" {
+		return name, &toolkit.AuthConfig{Type: authBearer, Token: id.Token}, nil
+	}
+	return name, nil, nil
+}
+
+// identityNames lists the configured identities in a stable order, anonymous last.
+func identityNames(cfg toolkit.UnittestConfig) []string {
+	names := make([]string, 0, len(cfg.Identities)+1)
+	for name := range cfg.Identities {
+		if strings.EqualFold(name, identityAnonymous) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, identityAnonymous)
+}
+
+// authzMatrixTests derives one test per identity from the endpoint's first
+// success test (or its first test) with the status implied by ep.Authz.
+// Identities missing from ep.Authz are expected to be allowed, anonymous to be
+// unauthorized. Credentials the template sends itself are dropped.
+func authzMatrixTests(ep toolkit.Endpoint, cfg toolkit.UnittestConfig) ([]toolkit.Test, error) {
+	if len(ep.Tests) == 0 {
+		return nil, nil
+	}
+	template := ep.Tests[0]
+	for _, tc := range ep.Tests {
+		if isSuccessTest(tc.ID) {
+			template = tc
+			break
+		}
+	}
+
+	headers, query := credentialNames(cfg)
+	var tests []toolkit.Test
+	for _, name := range identityNames(cfg) {
+		outcome, ok := ep.Authz[name]
+		if !ok {
+			outcome = authzAllow
+			if name == identityAnonymous {
+				outcome = authzUnauthorized
+			}
+		}
+		status, err := authzStatuses(outcome)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s identity %s: %w", ep.Name, name, err)
+		}
+
+		tc := toolkit.Test{
+			ID:          "authz-as-" + name,
+			As:          name,
+			Request:     template.Request,
+			Expectation: toolkit.Expectation{Status: status},
+		}
+		// never let the template's explicit credentials override the identity under test
+		tc.Request.Headers = cloneHeaders(template.Request.Headers)
+		for name := range tc.Request.Headers {
+			if slices.ContainsFunc(headers, func(h string) bool { return strings.EqualFold(h, name) }) {
+				delete(tc.Request.Headers, name)
+			}
+		}
+		tc.Request.Query = maps.Clone(template.Request.Query)
+		for _, name := range query {
+			delete(tc.Request.Query, name)
+		}
+		tests = append(tests, tc)
+	}
+	return tests, nil
+}
+
+// credentialNames lists the headers and query parameters the configured auth
+// providers send credentials in: Authorization and every api_key name.
+func credentialNames(cfg toolkit.UnittestConfig) (headers []string, query []string) {
+	headers = []string{"Authorization"}
+	configs := []*toolkit.AuthConfig{cfg.Auth}
+	for _, id := range cfg.Identities {
+		configs = append(configs, id.Auth)
+	}
+	for _, ac := range configs {
+		if ac == nil || !strings.EqualFold(strings.TrimSpace(ac.Type), authAPIKey) {
+			continue
+		}
+		name := stringsTrimOrDefault(ac.Name, "X-API-Key")
+		if strings.EqualFold(stringsTrimOrDefault(ac.In, "header"), "query") {
+			query = append(query, name)
+		} else {
+			headers = append(headers, name)
+		}
+	}
+	return headers, query
+}
+
+func authzStatuses(outcome string) ([]int, error) {
+	switch strings.ToLower(strings.TrimSpace(outcome)) {
+	case authzAllow:
+		return nil, nil // statusMatches treats empty as any 2xx
+	case authzForbidden:
+		return []int{http.StatusForbidden}, nil
+	case authzUnauthorized:
+		return []int{http.StatusUnauthorized}, nil
+	default:
+		return nil, fmt.Errorf("unknown authz outcome %q", outcome)
+	}
+}
@@ -0,0 +1,144 @@
+package reporter
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func matrixConfig() toolkit.UnittestConfig {
+	return toolkit.UnittestConfig{
+		AuthzMatrix: true,
+		Auth:        &toolkit.AuthConfig{Type: authAPIKey, APIKey: "suite", Name: "X-Suite-Key"},
+		Identities: map[string]toolkit.Identity{
+			"admin":  {Auth: &toolkit.AuthConfig{Type: authAPIKey, APIKey: "admin-key"}},
+			"reader": {Auth: &toolkit.AuthConfig{Type: authAPIKey, APIKey: "reader-key", In: "query", Name: "key"}},
+			"user":   {Token: "user-token"},
+		},
+	}
+}
+
+func TestAuthzMatrixTests(t *testing.T) {
+	template := toolkit.Test{ID: "success-valid-request", Request: toolkit.RequestSpecs{
+		Headers: map[string]string{"authorization": "Bearer stolen", "x-api-key": "stolen", "X-SUITE-KEY": "stolen", "X-Trace": "1"},
+		Query:   map[string]string{"key": "stolen", "page": "2"},
+	}}
+	tests := []struct {
+		name       string
+		tests      []toolkit.Test
+		authz      map[string]string
+		wantStatus map[string][]int // by identity
+		wantErr    string
+	}{
+		{
+			name:  "defaults",
+			tests: []toolkit.Test{template},
+			wantStatus: map[string][]int{
+				"admin": nil, "reader": nil, "user": nil, identityAnonymous: {http.StatusUnauthorized},
+			},
+		},
+		{
+			name:  "outcomes from authz",
+			tests: []toolkit.Test{{ID: "missing-auth"}, template},
+			authz: map[string]string{"reader": "Forbidden", "user": "unauthorized", identityAnonymous: "allow"},
+			wantStatus: map[string][]int{
+				"admin": nil, "reader": {http.StatusForbidden}, "user": {http.StatusUnauthorized}, identityAnonymous: nil,
+			},
+		},
+		{name: "unknown outcome", tests: []toolkit.Test{template}, authz: map[string]string{"user": "maybe"}, wantErr: `identity user: unknown authz outcome "maybe"`},
+		{name: "no tests"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := toolkit.Endpoint{Name: "/items", Method: "GET", Tests: tt.tests, Authz: tt.authz}
+			got, err := authzMatrixTests(ep, matrixConfig())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, tc := range got {
+				names = append(names, tc.As)
+				if tc.ID != "authz-as-"+tc.As {
+					t.Errorf("id %q for identity %q", tc.ID, tc.As)
+				}
+				if !slices.Equal(tc.Expectation.Status, tt.wantStatus[tc.As]) {
+					t.Errorf("%s: status %v, want %v", tc.As, tc.Expectation.Status, tt.wantStatus[tc.As])
+				}
+				if want := map[string]string{"X-Trace": "1"}; !maps.Equal(tc.Request.Headers, want) {
+					t.Errorf("%s: headers %v, want %v", tc.As, tc.Request.Headers, want)
+				}
+				if want := map[string]string{"page": "2"}; !maps.Equal(tc.Request.Query, want) {
+					t.Errorf("%s: query %v, want %v", tc.As, tc.Request.Query, want)
+				}
+			}
+			if want := slices.Sorted(maps.Keys(tt.wantStatus)); len(want) > 0 {
+				// named identities sorted, anonymous last
+				want = append(slices.DeleteFunc(want, func(n string) bool { return n == identityAnonymous }), identityAnonymous)
+				if !slices.Equal(names, want) {
+					t.Errorf("identities %v, want %v", names, want)
+				}
+			} else if len(got) > 0 {
+				t.Errorf("%d tests, want none", len(got))
+			}
+			if len(template.Request.Headers) != 4 || len(template.Request.Query) != 2 {
+				t.Error("template request modified")
+			}
+		})
+	}
+}
+
+// Every identity must reach the target with its own credentials even when
+// the template test carries another identity's.
+func TestAuthzMatrixSendsIdentityCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-API-Key") == "admin-key", r.URL.Query().Get("key") == "reader-key":
+			w.WriteHeader(http.StatusOK)
+		case r.Header.Get("Authorization") == "Bearer user-token":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	spec := toolkit.TestSpec{BaseURL: srv.URL, Endpoints: []toolkit.Endpoint{{
+		Name: "/items", Method: "GET", Authz: map[string]string{"user": authzForbidden},
+		Tests: []toolkit.Test{{
+			ID: "success-valid-request",
+			Request: toolkit.RequestSpecs{
+				Headers: map[string]string{"x-api-key": "admin-key", "authorization": "Bearer user-token"},
+				Query:   map[string]string{"key": "reader-key"},
+			},
+		}},
+	}}}
+	report, err := Run(context.Background(), spec, matrixConfig(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matrix int
+	for _, r := range report.Results {
+		if !strings.HasPrefix(r.TestID, "authz-as-") {
+			continue
+		}
+		matrix++
+		if !r.Passed {
+			t.Errorf("%s: status %d, %s", r.TestID, r.Status, r.Why)
+		}
+	}
+	if matrix != 4 {
+		t.Errorf("%d matrix cases, want 4", matrix)
+	}
+}
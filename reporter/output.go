@@ -0,0 +1,71 @@
+package reporter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Report formats persistReport can emit.
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// Output says where reports are written and in which formats. Zero values
+// fall back to ./synrax/report.json, ./synrax/report.md and
+// ./synrax/report.html.
+type Output struct {
+	Dir          string
+	JSONName     string
+	MarkdownName string
+	HTMLName     string
+	Formats      []string // empty emits every format
+}
+
+// Validate reports unknown formats and file names that escape Dir.
+func (o Output) Validate() error {
+	for _, f := range o.Formats {
+		switch strings.ToLower(strings.TrimSpace(f)) {
+		case FormatJSON, FormatMarkdown, FormatHTML:
+		default:
+			return fmt.Errorf("unknown report format %q (want %s, %s or %s)", f, FormatJSON, FormatMarkdown, FormatHTML)
+		}
+	}
+	for _, name := range []string{o.JSONName, o.MarkdownName, o.HTMLName} {
+		if name != "" && filepath.Base(name) != name {
+			return fmt.Errorf("report file name %q must not contain a directory, use the output directory instead", name)
+		}
+	}
+	return nil
+}
+
+func (o Output) emits(format string) bool {
+	if len(o.Formats) == 0 {
+		return true
+	}
+	for _, f := range o.Formats {
+		if strings.EqualFold(strings.TrimSpace(f), format) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Output) path(name string) (string, error) {
+	return filepath.Abs(filepath.Join(stringsTrimOrDefault(o.Dir, "synrax"), name))
+}
+
+// JSONPath is where report.json is written, and where `rerun` reads it back.
+func (o Output) JSONPath() (string, error) {
+	return o.path(stringsTrimOrDefault(o.JSONName, "report.json"))
+}
+
+func (o Output) MarkdownPath() (string, error) {
+	return o.path(stringsTrimOrDefault(o.MarkdownName, "report.md"))
+}
+
+func (o Output) HTMLPath() (string, error) {
+	return o.path(stringsTrimOrDefault(o.HTMLName, "report.html"))
+}
@@ -0,0 +1,210 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"synrax/toolkit"
+)
+
+const defaultRateLimitWindow = 60 * time.Second
+
+// quotaTracker remembers when each credential sent a request so rate-limit
+// tests know how much of the quota earlier tests already consumed.
+type quotaTracker struct {
+	mu   sync.Mutex
+	sent map[string][]time.Time
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{sent: make(map[string][]time.Time)}
+}
+
+func (q *quotaTracker) record(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sent[key] = append(q.sent[key], time.Now())
+}
+
+func (q *quotaTracker) usedSince(key string, since time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, t := range q.sent[key] {
+		if t.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// quotaKey identifies the credential a request is counted against. The quota
+// belongs to the credential, so every test sending the same token shares it,
+// whatever endpoint or identity name it uses.
+func quotaKey(st *runState, tc toolkit.Test) string {
+	for k, v := range tc.Request.Headers {
+		if strings.EqualFold(k, "Authorization") && v != "" {
+			return "header " + v
+		}
+	}
+	if !shouldInjectAuth(tc.ID) {
+		return identityAnonymous
+	}
+	_, ac, err := resolveAuth(st.cfg, tc.As)
+	if err != nil || ac == nil {
+		return identityAnonymous
+	}
+	return strings.Join([]string{ac.Type, ac.Token, ac.Username, ac.Password, ac.APIKey,
+		ac.TokenURL, ac.ClientID, ac.KeyFile, ac.Issuer, ac.Subject}, "\x00")
+}
+
+// rateLimitSpecFor returns the explicit rate_limit block, or one derived from
+// the legacy `rate-limit-exceeded-N` test id.
+func rateLimitSpecFor(tc toolkit.Test) (toolkit.RateLimitSpec, bool) {
+	if tc.RateLimit != nil {
+		return *tc.RateLimit, true
+	}
+	if limit, ok := parseRateLimitTestID(tc.ID); ok {
+		return toolkit.RateLimitSpec{Limit: limit}, true
+	}
+	return toolkit.RateLimitSpec{}, false
+}
+
+// runRateLimit fills cr with the last observed response and returns its
+// headers. It returns false when the case already failed and the regular
+// assertions must be skipped.
+func runRateLimit(ctx context.Context, st *runState, ep toolkit.Endpoint, tc toolkit.Test, rl toolkit.RateLimitSpec, fullURL string, cr *toolkit.UnittestCaseResult) (http.Header, bool) {
+	if rl.Limit <= 0 {
+		rl.Limit = 1
+	}
+	window := defaultRateLimitWindow
+	if rl.WindowSeconds > 0 {
+		window = time.Duration(rl.WindowSeconds) * time.Second
+	}
+
+	key := quotaKey(st, tc)
+	used := st.quota.usedSince(key, time.Now().Add(-window))
+	needed := rl.Limit - used + 1
+	if needed < 1 {
+		needed = 1
+	}
+	info := &toolkit.RateLimitResult{
+		Limit:             rl.Limit,
+		AlreadyUsed:       used,
+		ExpectedLimitedAt: needed,
+	}
+	cr.RateLimit = info
+	slog.InfoContext(ctx, "tester.rate_limit: start", "limit", rl.Limit, "already_used", used, "sending", needed)
+
+	var limitedAt time.Time
+	var lastHeader http.Header
+	for i := 1; i <= needed; i++ {
+		res, runErr := executeRequest(ctx, st, ep, tc, fullURL)
+		cr.LatencyMS += res.LatencyMS
+		info.Sent = i
+		if runErr != nil {
+			slog.DebugContext(ctx, "tester.run_one: request failed", "error", runErr)
+			markRequestFailure(cr, runErr)
+			return nil, false
+		}
+		cr.Status = res.Status
+		cr.Body = res.Body
+		lastHeader = res.Header
+		if res.Status == http.StatusTooManyRequests {
+			info.FirstLimitedAt = i
+			info.RetryAfter = res.Header.Get("Retry-After")
+			limitedAt = time.Now()
+			break
+		}
+	}
+
+	if info.FirstLimitedAt == 0 {
+		slog.InfoContext(ctx, "tester.rate_limit: limit never reached", "sent", info.Sent)
+		return lastHeader, true // the status assertion, 429 by default, reports it
+	}
+	if info.FirstLimitedAt < info.ExpectedLimitedAt {
+		// the server enforces a lower limit than the spec documents
+		slog.InfoContext(ctx, "tester.rate_limit: limited early", "first_limited_at", info.FirstLimitedAt, "expected", info.ExpectedLimitedAt)
+		cr.Passed = false
+		cr.Failure = "rate_limit_early"
+		cr.Why = fmt.Sprintf("Rate limited at request %d, expected at request %d (limit %d, %d already used).",
+			info.FirstLimitedAt, info.ExpectedLimitedAt, rl.Limit, info.AlreadyUsed)
+		cr.Error = fmt.Sprintf("first 429 at request %d of %d", info.FirstLimitedAt, info.ExpectedLimitedAt)
+		return nil, false
+	}
+	if !rl.VerifyRecovery {
+		return lastHeader, true
+	}
+
+	maxWait := window + 5*time.Second
+	if rl.MaxWaitSeconds > 0 {
+		maxWait = time.Duration(rl.MaxWaitSeconds) * time.Second
+	}
+	wait := window
+	if d, ok := parseRetryAfter(info.RetryAfter, limitedAt); ok {
+		wait = d
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	slog.InfoContext(ctx, "tester.rate_limit: waiting for recovery", "wait", wait)
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return nil, false // the caller marks interrupted cases as skipped
+	}
+
+	res, runErr := executeRequest(ctx, st, ep, tc, fullURL)
+	recovered := runErr == nil && res.Status != http.StatusTooManyRequests
+	info.Recovered = &recovered
+	if !recovered {
+		cr.Passed = false
+		cr.Failure = "rate_limit_no_recovery"
+		cr.Why = fmt.Sprintf("Rate limit did not reset after waiting %s.", wait)
+		if runErr != nil {
+			cr.Error = runErr.Error()
+		} else {
+			cr.Error = fmt.Sprintf("still limited after recovery wait (status=%d)", res.Status)
+		}
+		return nil, false
+	}
+	return lastHeader, true
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string, from time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(from)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func parseRateLimitTestID(testID string) (int, bool) {
+	id := strings.ToLower(strings.TrimSpace(testID))
+	const prefix = "rate-limit-exceeded-"
+	if !strings.HasPrefix(id, prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(id, prefix))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
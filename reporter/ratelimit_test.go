@@ -0,0 +1,127 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+// limitedServer answers 429 once a bearer token sent more than limit
+// requests; limit 0 never limits.
+func limitedServer(t *testing.T, limit int) *httptest.Server {
+	var mu sync.Mutex
+	sent := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := r.Header.Get("Authorization")
+		sent[token]++
+		if limit > 0 && sent[token] > limit {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRateLimitCase(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverLimit int
+		status      []int
+		wantOutcome string
+		wantFailure string
+		wantSent    int
+	}{
+		{"limit enforced", 3, nil, toolkit.OutcomePassed, "", 4},
+		{"never limited", 0, nil, toolkit.OutcomeFailed, "status_mismatch", 4},
+		{"limited early", 1, nil, toolkit.OutcomeFailed, "rate_limit_early", 2},
+		{"limited early, explicit status", 2, []int{http.StatusTooManyRequests}, toolkit.OutcomeFailed, "rate_limit_early", 3},
+		{"explicit status", 3, []int{http.StatusTooManyRequests}, toolkit.OutcomePassed, "", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := limitedServer(t, tt.serverLimit)
+			spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{
+				Name: "/items", Method: "GET",
+				Tests: []toolkit.Test{{
+					ID:          "quota",
+					RateLimit:   &toolkit.RateLimitSpec{Limit: 3},
+					Expectation: toolkit.Expectation{Status: tt.status},
+				}},
+			}}}
+			rep, err := Run(context.Background(), spec, toolkit.UnittestConfig{BaseURL: srv.URL, AuthToken: "t1"}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := rep.Results[0]
+			if res.Outcome != tt.wantOutcome || res.Failure != tt.wantFailure {
+				t.Errorf("outcome %s failure %q (%s), want %s %q", res.Outcome, res.Failure, res.Why, tt.wantOutcome, tt.wantFailure)
+			}
+			if res.RateLimit.Sent != tt.wantSent {
+				t.Errorf("sent %d, want %d", res.RateLimit.Sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestRateLimitQuotaSharedAcrossTests(t *testing.T) {
+	srv := limitedServer(t, 3)
+	cfg := toolkit.UnittestConfig{
+		BaseURL: srv.URL,
+		Identities: map[string]toolkit.Identity{
+			"alice":      {Token: "shared"},
+			"alice-copy": {Token: "shared"},
+			"bob":        {Token: "other"},
+		},
+	}
+	spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{
+		{Name: "/a", Method: "GET", Tests: []toolkit.Test{{ID: "one", As: "alice"}, {ID: "two", As: "alice"}, {ID: "three", As: "bob"}}},
+		{Name: "/b", Method: "GET", Tests: []toolkit.Test{{ID: "quota", As: "alice-copy", RateLimit: &toolkit.RateLimitSpec{Limit: 3}}}},
+	}}
+	rep, err := Run(context.Background(), spec, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := rep.Results[3]
+	if res.Outcome != toolkit.OutcomePassed {
+		t.Fatalf("outcome %s: %s", res.Outcome, res.Why)
+	}
+	if res.RateLimit.AlreadyUsed != 2 || res.RateLimit.Sent != 2 {
+		t.Errorf("already used %d, sent %d; want 2 and 2", res.RateLimit.AlreadyUsed, res.RateLimit.Sent)
+	}
+}
+
+func TestQuotaKey(t *testing.T) {
+	st := &runState{cfg: toolkit.UnittestConfig{
+		AuthToken: "default",
+		Identities: map[string]toolkit.Identity{
+			"a": {Token: "t1"},
+			"b": {Token: "t1"},
+			"c": {Token: "t2"},
+		},
+	}}
+	tests := []struct {
+		name string
+		x, y toolkit.Test
+		same bool
+	}{
+		{"same token, different identity", toolkit.Test{As: "a"}, toolkit.Test{As: "b"}, true},
+		{"different token", toolkit.Test{As: "a"}, toolkit.Test{As: "c"}, false},
+		{"explicit header wins", toolkit.Test{As: "a", Request: toolkit.RequestSpecs{Headers: map[string]string{"authorization": "Bearer x"}}},
+			toolkit.Test{As: "c", Request: toolkit.RequestSpecs{Headers: map[string]string{"Authorization": "Bearer x"}}}, true},
+		{"no auth injected", toolkit.Test{ID: "missing-auth"}, toolkit.Test{As: "anonymous"}, true},
+		{"default credential", toolkit.Test{}, toolkit.Test{As: "a"}, false},
+	}
+	for _, tt := range tests {
+		if got := quotaKey(st, tt.x) == quotaKey(st, tt.y); got != tt.same {
+			t.Errorf("%s: same key %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
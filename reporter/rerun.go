@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"synrax/toolkit"
+)
+
+// Rerun repeats the cases that did not pass in a previous report.json using
+// the spec saved with it, then persists a merged report where rerun cases
+// carry their previous outcome.
+func Rerun(ctx context.Context, fromPath string, cfg toolkit.UnittestConfig, opts Options) (toolkit.UnittestReport, error) {
+	prev, err := readReport(fromPath)
+	if err != nil {
+		return toolkit.UnittestReport{}, fmt.Errorf("%w: %v", ErrConfig, err)
+	}
+	if prev.Spec == nil {
+		return toolkit.UnittestReport{}, fmt.Errorf("%w: report %q has no saved spec; run `read` again to produce one", ErrSpec, fromPath)
+	}
+
+	var cases []CaseRef
+	for _, r := range prev.Results {
+		if !r.Passed {
+			cases = append(cases, CaseRef{Endpoint: r.Endpoint, Method: r.Method, TestID: r.TestID})
+		}
+	}
+	slog.Info("runner.rerun: start", "from", fromPath, "total", len(prev.Results), "rerun", len(cases))
+	if len(cases) == 0 {
+		slog.Info("runner.rerun: nothing to rerun, every case passed")
+		return prev, nil
+	}
+
+	spec := *prev.Spec
+	if spec.BaseURL == "" {
+		spec.BaseURL = cfg.BaseURL
+	}
+	opts.Filter.Cases = cases
+	fresh, err := Run(ctx, spec, cfg, opts)
+	if err != nil {
+		return toolkit.UnittestReport{}, err
+	}
+
+	merged := mergeRerun(prev, fresh)
+	merged.RerunOf = fromPath
+	merged.Spec = &spec
+	merged.SpecHash = toolkit.SpecHash(spec)
+	merged.Commit = opts.Commit
+	merged.RunID = opts.RunID
+	merged.Filtered = prev.Filtered // the merge keeps every case of prev
+	if opts.Baseline != nil {
+		merged.Baseline = compareBaseline(merged, opts.Baseline)
+	}
+	slog.Info("runner.rerun: complete", "total", merged.Summary.Total, "passed", merged.Summary.Passed, "failed", merged.Summary.Failed, "fixed", merged.Summary.Fixed)
+
+	if err := persistReport(&merged, opts.Output); err != nil {
+		return toolkit.UnittestReport{}, err
+	}
+	return merged, nil
+}
+
+// mergeRerun keeps the previous order and replaces every rerun case with its
+// fresh result. A case an interrupted rerun skipped keeps its previous result.
+// Hooks come from the fresh run only.
+func mergeRerun(prev toolkit.UnittestReport, fresh toolkit.UnittestReport) toolkit.UnittestReport {
+	byKey := make(map[string]toolkit.UnittestCaseResult, len(fresh.Results))
+	for _, r := range fresh.Results {
+		byKey[resultRef(r).key()] = r
+	}
+
+	var out toolkit.UnittestReport
+	out.Interrupted = fresh.Interrupted
+	out.StartedAt = fresh.StartedAt
+	out.DurationMS = fresh.DurationMS
+	recordHooks(&out, fresh.Hooks)
+	for _, old := range prev.Results {
+		res, ok := byKey[resultRef(old).key()]
+		if !ok || resultOutcome(res) == toolkit.OutcomeSkipped {
+			tally(&out, old)
+			continue
+		}
+		res.Rerun = true
+		res.PreviousOutcome = resultOutcome(old)
+		tally(&out, res)
+		if res.Passed {
+			out.Summary.Fixed++
+		}
+	}
+	return out
+}
+
+func resultRef(r toolkit.UnittestCaseResult) CaseRef {
+	return CaseRef{Endpoint: r.Endpoint, Method: r.Method, TestID: r.TestID}
+}
+
+func readReport(path string) (toolkit.UnittestReport, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return toolkit.UnittestReport{}, fmt.Errorf("read report %q: %w", path, err)
+	}
+	var report toolkit.UnittestReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return toolkit.UnittestReport{}, fmt.Errorf("decode report %q: %w", path, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package reporter
+
+import (
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func TestMergeRerun(t *testing.T) {
+	const (
+		passed  = toolkit.OutcomePassed
+		failed  = toolkit.OutcomeFailed
+		errored = toolkit.OutcomeErrored
+		skipped = toolkit.OutcomeSkipped
+	)
+	result := func(id, outcome string) toolkit.UnittestCaseResult {
+		r := caseResult(id, outcome)
+		r.Passed = outcome == passed
+		return r
+	}
+	type want struct {
+		outcome  string
+		rerun    bool
+		previous string
+	}
+	tests := []struct {
+		name        string
+		prev        []toolkit.UnittestCaseResult
+		fresh       []toolkit.UnittestCaseResult
+		interrupted bool
+		want        []want // per case of prev, in its order
+		summary     toolkit.UnittestSummary
+	}{
+		{
+			name:    "fixed and still failing",
+			prev:    []toolkit.UnittestCaseResult{result("a", passed), result("b", failed), result("c", errored)},
+			fresh:   []toolkit.UnittestCaseResult{result("c", failed), result("b", passed)},
+			want:    []want{{passed, false, ""}, {passed, true, failed}, {failed, true, errored}},
+			summary: toolkit.UnittestSummary{Total: 3, Passed: 2, Failed: 1, Fixed: 1},
+		},
+		{
+			// b is no longer in the spec, so the rerun has no result for it
+			name:    "case not rerun",
+			prev:    []toolkit.UnittestCaseResult{result("a", failed), result("b", failed)},
+			fresh:   []toolkit.UnittestCaseResult{result("a", passed)},
+			want:    []want{{passed, true, failed}, {failed, false, ""}},
+			summary: toolkit.UnittestSummary{Total: 2, Passed: 1, Failed: 1, Fixed: 1},
+		},
+		{
+			name:        "interrupted rerun",
+			prev:        []toolkit.UnittestCaseResult{result("a", failed), result("b", errored)},
+			fresh:       []toolkit.UnittestCaseResult{result("a", passed), result("b", skipped)},
+			interrupted: true,
+			want:        []want{{passed, true, failed}, {errored, false, ""}},
+			summary:     toolkit.UnittestSummary{Total: 2, Passed: 1, Errored: 1, Fixed: 1},
+		},
+		{
+			name:    "previous fixed count dropped",
+			prev:    []toolkit.UnittestCaseResult{result("a", failed)},
+			fresh:   []toolkit.UnittestCaseResult{result("a", failed)},
+			want:    []want{{failed, true, failed}},
+			summary: toolkit.UnittestSummary{Total: 1, Failed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := toolkit.UnittestReport{Results: tt.prev, Summary: toolkit.UnittestSummary{Fixed: 5}}
+			fresh := toolkit.UnittestReport{Results: tt.fresh, Interrupted: tt.interrupted}
+			got := mergeRerun(prev, fresh)
+			if got.Interrupted != tt.interrupted {
+				t.Errorf("interrupted %v, want %v", got.Interrupted, tt.interrupted)
+			}
+			if got.Summary != tt.summary {
+				t.Errorf("summary %+v, want %+v", got.Summary, tt.summary)
+			}
+			if len(got.Results) != len(tt.want) {
+				t.Fatalf("%d results, want %d", len(got.Results), len(tt.want))
+			}
+			for i, w := range tt.want {
+				r := got.Results[i]
+				if r.TestID != tt.prev[i].TestID {
+					t.Errorf("result %d is %q, want %q", i, r.TestID, tt.prev[i].TestID)
+				}
+				if r.Outcome != w.outcome || r.Rerun != w.rerun || r.PreviousOutcome != w.previous {
+					t.Errorf("%s: outcome %q rerun %v previous %q, want %+v", r.TestID, r.Outcome, r.Rerun, r.PreviousOutcome, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"synrax/toolkit"
+)
+
+// failures retried when a policy names neither statuses nor failure types
+var (
+	defaultRetryFailures = []string{"transport_error", "timeout"}
+	defaultRetryStatuses = []int{502, 503, 504}
+)
+
+// retryPolicyFor returns the most specific retry policy of the case.
+// Rate-limit tests are never retried, every attempt would spend quota.
+func retryPolicyFor(st *runState, ep toolkit.Endpoint, tc toolkit.Test) (toolkit.RetryPolicy, bool) {
+	if _, ok := rateLimitSpecFor(tc); ok {
+		return toolkit.RetryPolicy{}, false
+	}
+	for _, p := range []*toolkit.RetryPolicy{tc.Retry, ep.Retry, st.cfg.Retry} {
+		if p != nil {
+			return *p, p.Count > 0
+		}
+	}
+	return toolkit.RetryPolicy{}, false
+}
+
+// shouldRetry reports whether a failed attempt matches the policy. Errored
+// cases are never retried, repeating a broken test gives the same result.
+func shouldRetry(p toolkit.RetryPolicy, res toolkit.UnittestCaseResult) bool {
+	if res.Passed || caseOutcome(res) == toolkit.OutcomeErrored {
+		return false
+	}
+	statuses, failures := p.OnStatus, p.OnFailure
+	if len(statuses) == 0 && len(failures) == 0 {
+		statuses, failures = defaultRetryStatuses, defaultRetryFailures
+	}
+	for _, f := range failures {
+		if strings.EqualFold(strings.TrimSpace(f), res.Failure) {
+			return true
+		}
+	}
+	if res.Status == 0 {
+		return false
+	}
+	for _, s := range statuses {
+		if s == res.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// runWithRetry executes a case under its retry policy. Every attempt is
+// recorded once the case was retried; a case that passes after a failed
+// attempt is reported as flaky.
+func runWithRetry(ctx context.Context, st *runState, ep toolkit.Endpoint, tc toolkit.Test) toolkit.UnittestCaseResult {
+	res := runOne(ctx, st, ep, tc)
+	policy, ok := retryPolicyFor(st, ep, tc)
+	if !ok {
+		return res
+	}
+
+	var attempts []toolkit.CaseAttempt
+	for attempt := 1; attempt <= policy.Count && shouldRetry(policy, res); attempt++ {
+		attempts = append(attempts, caseAttempt(res))
+		select {
+		case <-time.After(time.Duration(policy.DelayMS) * time.Millisecond):
+		case <-ctx.Done():
+			return res // the caller marks interrupted cases as skipped
+		}
+		slog.InfoContext(ctx, "tester.retry: retrying", "attempt", attempt+1, "failure", res.Failure, "status", res.Status)
+		res = runOne(ctx, st, ep, tc)
+	}
+	if len(attempts) == 0 {
+		return res
+	}
+	res.Attempts = append(attempts, caseAttempt(res))
+	if res.Passed {
+		res.Outcome = toolkit.OutcomeFlaky
+		slog.WarnContext(ctx, "tester.retry: flaky", "attempts", len(res.Attempts))
+	}
+	return res
+}
+
+func caseAttempt(res toolkit.UnittestCaseResult) toolkit.CaseAttempt {
+	return toolkit.CaseAttempt{
+		Status:    res.Status,
+		Failure:   res.Failure,
+		Error:     res.Error,
+		LatencyMS: res.LatencyMS,
+	}
+}
@@ -0,0 +1,137 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"synrax/toolkit"
+)
+
+func TestShouldRetry(t *testing.T) {
+	failed := func(status int, failure string) toolkit.UnittestCaseResult {
+		return toolkit.UnittestCaseResult{Status: status, Failure: failure}
+	}
+	tests := []struct {
+		name   string
+		policy toolkit.RetryPolicy
+		res    toolkit.UnittestCaseResult
+		want   bool
+	}{
+		{"passed", toolkit.RetryPolicy{Count: 2}, toolkit.UnittestCaseResult{Passed: true, Status: 503}, false},
+		{"errored", toolkit.RetryPolicy{Count: 2}, failed(0, "request_build_error"), false},
+		{"default status", toolkit.RetryPolicy{Count: 2}, failed(503, "status_mismatch"), true},
+		{"default failure", toolkit.RetryPolicy{Count: 2}, failed(0, "transport_error"), true},
+		{"default ignores 500", toolkit.RetryPolicy{Count: 2}, failed(500, "status_mismatch"), false},
+		{"default ignores content", toolkit.RetryPolicy{Count: 2}, failed(200, "content_mismatch"), false},
+		{"own status", toolkit.RetryPolicy{Count: 2, OnStatus: []int{500}}, failed(500, "status_mismatch"), true},
+		{"own status replaces defaults", toolkit.RetryPolicy{Count: 2, OnStatus: []int{500}}, failed(503, "status_mismatch"), false},
+		{"own failure, case and space", toolkit.RetryPolicy{Count: 2, OnFailure: []string{" Timeout "}}, failed(0, "timeout"), true},
+		{"own failure only", toolkit.RetryPolicy{Count: 2, OnFailure: []string{"timeout"}}, failed(502, "status_mismatch"), false},
+	}
+	for _, tt := range tests {
+		if got := shouldRetry(tt.policy, tt.res); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// flakyServer answers 503 to the first fails requests, then 200, and
+// records when each request arrived.
+func flakyServer(t *testing.T, fails int) (*httptest.Server, func() []time.Time) {
+	var mu sync.Mutex
+	var seen []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, time.Now())
+		n := len(seen)
+		mu.Unlock()
+		if n <= fails {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Time(nil), seen...)
+	}
+}
+
+func TestRunWithRetry(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	tests := []struct {
+		name         string
+		fails        int
+		retry        *toolkit.RetryPolicy
+		wantOutcome  string
+		wantRequests int
+	}{
+		{"passes first time", 0, &toolkit.RetryPolicy{Count: 2, DelayMS: 40}, toolkit.OutcomePassed, 1},
+		{"flaky", 1, &toolkit.RetryPolicy{Count: 2, DelayMS: 40}, toolkit.OutcomeFlaky, 2},
+		{"retries exhausted", 5, &toolkit.RetryPolicy{Count: 2, DelayMS: 40}, toolkit.OutcomeFailed, 3},
+		{"no policy", 1, nil, toolkit.OutcomeFailed, 1},
+		{"zero count", 1, &toolkit.RetryPolicy{DelayMS: 40}, toolkit.OutcomeFailed, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, seen := flakyServer(t, tt.fails)
+			spec := toolkit.TestSpec{Endpoints: []toolkit.Endpoint{{
+				Name: "/items", Method: "GET",
+				Tests: []toolkit.Test{{ID: "list", Retry: tt.retry}},
+			}}}
+			rep, err := Run(context.Background(), spec, toolkit.UnittestConfig{BaseURL: srv.URL}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := rep.Results[0]
+			if res.Outcome != tt.wantOutcome {
+				t.Errorf("outcome %s, want %s", res.Outcome, tt.wantOutcome)
+			}
+			times := seen()
+			if len(times) != tt.wantRequests {
+				t.Fatalf("%d requests, want %d", len(times), tt.wantRequests)
+			}
+			if tt.wantRequests > 1 && len(res.Attempts) != tt.wantRequests {
+				t.Errorf("%d attempts recorded, want %d", len(res.Attempts), tt.wantRequests)
+			}
+			for i := 1; i < len(times); i++ {
+				if gap := times[i].Sub(times[i-1]); gap < delay {
+					t.Errorf("attempt %d after %s, want at least %s", i+1, gap, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	suite := &toolkit.RetryPolicy{Count: 1}
+	endpoint := &toolkit.RetryPolicy{Count: 2}
+	test := &toolkit.RetryPolicy{Count: 3}
+	tests := []struct {
+		name      string
+		cfg, ep   *toolkit.RetryPolicy
+		tc        toolkit.Test
+		wantCount int
+		wantOK    bool
+	}{
+		{"none", nil, nil, toolkit.Test{}, 0, false},
+		{"suite", suite, nil, toolkit.Test{}, 1, true},
+		{"endpoint over suite", suite, endpoint, toolkit.Test{}, 2, true},
+		{"test over endpoint", suite, endpoint, toolkit.Test{Retry: test}, 3, true},
+		{"test disables", suite, endpoint, toolkit.Test{Retry: &toolkit.RetryPolicy{}}, 0, false},
+		{"rate limit never retried", suite, endpoint, toolkit.Test{Retry: test, RateLimit: &toolkit.RateLimitSpec{Limit: 3}}, 0, false},
+	}
+	for _, tt := range tests {
+		st := &runState{cfg: toolkit.UnittestConfig{Retry: tt.cfg}}
+		p, ok := retryPolicyFor(st, toolkit.Endpoint{Retry: tt.ep}, tt.tc)
+		if ok != tt.wantOK || p.Count != tt.wantCount {
+			t.Errorf("%s: count %d ok %v, want %d %v", tt.name, p.Count, ok, tt.wantCount, tt.wantOK)
+		}
+	}
+}
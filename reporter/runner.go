@@ -1,100 +1,142 @@
 package reporter
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
 	"synrax/toolkit"
 )
 
+// Error classes callers tell apart, the cli maps them to exit codes.
+var (
+	ErrConfig = errors.New("invalid configuration") // input files, filters or repo config
+	ErrSpec   = errors.New("spec generation")       // the server returned no usable spec
+)
+
 // main exporting function
-func RunUnittest(filepath string, config toolkit.UnittestConfig, repoID string) (toolkit.UnittestReport, error) {
+func RunUnittest(ctx context.Context, filepath string, config toolkit.UnittestConfig, repoID string, opts Options) (toolkit.UnittestReport, error) {
 
 	// read given file path documentation
 	docBytes, err := os.ReadFile(filepath)
 	if err != nil {
-		log.Printf("runner: documentation read failed file=%s error=%v", filepath, err)
-		return toolkit.UnittestReport{}, err
+		slog.Error("runner: documentation read failed", "file", filepath, "error", err)
+		return toolkit.UnittestReport{}, fmt.Errorf("%w: %v", ErrConfig, err)
 	}
 	documentation := string(docBytes)
 
-	log.Printf("runner: documentation loaded bytes=%d", len(docBytes))
+	slog.Info("runner: documentation loaded", "bytes", len(docBytes))
 
 	// call spec API from server
-	spec, err := toolkit.SynraxSpecCaller(documentation, config, repoID)
+	spec, err := toolkit.SynraxSpecCaller(ctx, documentation, config, repoID)
 	if err != nil {
-		log.Printf("runner: spec fetch failed error=%v", err)
-		return toolkit.UnittestReport{}, err
+		slog.Error("runner: spec fetch failed", "error", err)
+		// %w twice so a cancelled fetch is still context.Canceled
+		return toolkit.UnittestReport{}, fmt.Errorf("%w: %w", ErrSpec, err)
 	}
-	log.Printf("runner: spec fetched endpoints=%d", len(spec.Endpoints))
+	slog.Info("runner: spec fetched", "endpoints", len(spec.Endpoints))
 	if len(spec.Endpoints) == 0 {
-		return toolkit.UnittestReport{}, fmt.Errorf("received empty test spec from server")
+		return toolkit.UnittestReport{}, fmt.Errorf("%w: received empty test spec from server", ErrSpec)
 	}
 	// build documentation
-	report, err := BuildReportFromDocumentation(spec, config)
+	report, err := BuildReportFromDocumentation(ctx, spec, config, opts)
 	if err != nil {
-		log.Printf("runner: report build failed error=%v", err)
+		slog.Error("runner: report build failed", "error", err)
 		return toolkit.UnittestReport{}, err
 	}
 
-	log.Printf("runner: completed")
+	slog.Info("runner: completed")
 	return report, err
 }
 
-func BuildReportFromDocumentation(spec toolkit.TestSpec, cfg toolkit.UnittestConfig) (toolkit.UnittestReport, error) {
-	log.Printf("runner.build: start base_from_spec=%s base_from_config=%s endpoints=%d", spec.BaseURL, cfg.BaseURL, len(spec.Endpoints))
+func BuildReportFromDocumentation(ctx context.Context, spec toolkit.TestSpec, cfg toolkit.UnittestConfig, opts Options) (toolkit.UnittestReport, error) {
+	slog.Info("runner.build: start", "base_from_spec", spec.BaseURL, "base_from_config", cfg.BaseURL, "endpoints", len(spec.Endpoints))
 
 	if spec.BaseURL == "" {
 		spec.BaseURL = cfg.BaseURL
-		log.Printf("runner.build: spec base empty; fallback to config base=%s", spec.BaseURL)
+		slog.Info("runner.build: spec base empty, using config base", "base", spec.BaseURL)
 	}
 
-	report := Run(spec, cfg) // run test with given test spec
-	report.Persisted = false
-	log.Printf("runner.build: test run complete total=%d passed=%d failed=%d", report.Summary.Total, report.Summary.Passed, report.Summary.Failed)
-
-	// write report.json
-	jsonPath, err := filepath.Abs("./synrax/report.json")
+	report, err := Run(ctx, spec, cfg, opts) // run test with given test spec
 	if err != nil {
-		log.Printf("runner.build: failed resolve report path error=%v", err)
 		return toolkit.UnittestReport{}, err
 	}
-	reportPath, err := filepath.Abs("./synrax/report.md")
-	if err != nil {
-		log.Printf("runner.build: failed resolve report path error=%v", err)
+	report.Spec = &spec // saved so `rerun` can repeat cases without regenerating the spec
+	report.SpecHash = toolkit.SpecHash(spec)
+	report.Commit = opts.Commit
+	report.RunID = opts.RunID
+	if opts.Baseline != nil {
+		report.Baseline = compareBaseline(report, opts.Baseline)
+	}
+	slog.Info("runner.build: test run complete", "total", report.Summary.Total, "passed", report.Summary.Passed, "failed", report.Summary.Failed, "errored", report.Summary.Errored, "skipped", report.Summary.Skipped, "interrupted", report.Interrupted)
+
+	if err := persistReport(&report, opts.Output); err != nil {
 		return toolkit.UnittestReport{}, err
 	}
+	return report, nil
+}
+
+// persistReport writes the formats selected by out and marks the report
+// persisted. Every file is replaced atomically.
+func persistReport(report *toolkit.UnittestReport, out Output) error {
+	report.Persisted = false
+
+	if out.emits(FormatMarkdown) {
+		reportPath, err := out.MarkdownPath()
+		if err != nil {
+			slog.Error("runner.build: failed resolve report path", "error", err)
+			return err
+		}
+		if err := toolkit.ParseUnittest(reportPath, *report); err != nil {
+			slog.Error("runner.build: failed write report", "path", reportPath, "error", err)
+			return fmt.Errorf("persist report markdown: %w", err)
+		}
+	}
 
-	if err := toolkit.ParseUnittest(reportPath, report); err != nil {
-		log.Printf("runner.build: failed write report path=%s error=%v", reportPath, err)
-		return toolkit.UnittestReport{}, fmt.Errorf("persist report json: %w", err)
+	if out.emits(FormatHTML) {
+		htmlPath, err := out.HTMLPath()
+		if err != nil {
+			slog.Error("runner.build: failed resolve report path", "error", err)
+			return err
+		}
+		if err := toolkit.WriteHTMLReport(htmlPath, *report); err != nil {
+			slog.Error("runner.build: failed write report", "path", htmlPath, "error", err)
+			return fmt.Errorf("persist report html: %w", err)
+		}
 	}
 
-	if err := writeJSON(jsonPath, report); err != nil {
-		log.Printf("runner.build: failed write report path=%s error=%v", jsonPath, err)
-		return toolkit.UnittestReport{}, fmt.Errorf("persist report json: %w", err)
+	if out.emits(FormatJSON) {
+		jsonPath, err := out.JSONPath()
+		if err != nil {
+			slog.Error("runner.build: failed resolve report path", "error", err)
+			return err
+		}
+		if err := writeJSON(jsonPath, *report); err != nil {
+			slog.Error("runner.build: failed write report", "path", jsonPath, "error", err)
+			return fmt.Errorf("persist report json: %w", err)
+		}
 	}
 	report.Persisted = true
 
-	return report, nil
+	return nil
 }
 
 func writeJSON(path string, data toolkit.UnittestReport) error {
-	log.Printf("runner.write_json: writing file=%s", path)
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("prepare output directory for %q: %w", path, err)
-	}
+	slog.Info("runner.write_json: writing", "file", path)
 	b, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal json %q: %w", path, err)
 	}
-	if err := os.WriteFile(path, b, 0o644); err != nil {
-		return fmt.Errorf("write json file %q: %w", path, err)
-	}
-	return nil
+	return toolkit.WriteFileAtomic(path, func(w io.Writer) error {
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("write json file %q: %w", path, err)
+		}
+		return nil
+	})
 }
 
 func stringsTrimOrDefault(value, fallback string) string {
@@ -0,0 +1,204 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"synrax/toolkit"
+)
+
+// Stream formats for Options.Stream.
+const (
+	StreamNDJSON = "ndjson"
+	StreamTAP    = "tap"
+)
+
+// Stream reports progress while a run executes instead of only once it
+// returns. NDJSON writes one toolkit.RunEvent per line; TAP writes TAP
+// version 13 with a YAML block per failing case.
+type Stream struct {
+	Format string
+	Writer io.Writer
+}
+
+func (s Stream) Validate() error {
+	switch s.Format {
+	case StreamNDJSON, StreamTAP:
+	default:
+		return fmt.Errorf("unknown stream format %q (want %s or %s)", s.Format, StreamNDJSON, StreamTAP)
+	}
+	if s.Writer == nil {
+		return fmt.Errorf("stream %s has no writer", s.Format)
+	}
+	return nil
+}
+
+// eventSink receives the progress of a Run. Every planned case gets exactly
+// one caseFinish, including skipped and errored ones.
+type eventSink interface {
+	runStart(cases int)
+	caseStart(ep toolkit.Endpoint, tc toolkit.Test)
+	caseFinish(res toolkit.UnittestCaseResult)
+	runFinish(rep toolkit.UnittestReport)
+}
+
+func newEventSink(s *Stream) eventSink {
+	if s == nil {
+		return nopSink{}
+	}
+	w := &streamWriter{w: s.Writer, format: s.Format}
+	if s.Format == StreamTAP {
+		return &tapSink{out: w}
+	}
+	return &ndjsonSink{out: w}
+}
+
+type nopSink struct{}
+
+func (nopSink) runStart(int)                             {}
+func (nopSink) caseStart(toolkit.Endpoint, toolkit.Test) {}
+func (nopSink) caseFinish(toolkit.UnittestCaseResult)    {}
+func (nopSink) runFinish(toolkit.UnittestReport)         {}
+
+// streamWriter writes whole events at once. A broken consumer must not fail
+// the run, so the first write error is logged and later events are dropped.
+type streamWriter struct {
+	w      io.Writer
+	format string
+	broken bool
+}
+
+func (s *streamWriter) write(b []byte) {
+	if s.broken {
+		return
+	}
+	if _, err := s.w.Write(b); err != nil {
+		s.broken = true
+		slog.Error("tester.stream: write failed, stream disabled", "format", s.format, "error", err)
+	}
+}
+
+type ndjsonSink struct {
+	out *streamWriter
+	seq int
+}
+
+func (n *ndjsonSink) emit(ev toolkit.RunEvent) {
+	n.seq++
+	ev.Seq = n.seq
+	ev.Time = time.Now().UTC()
+	b, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("tester.stream: marshal failed", "event", ev.Event, "error", err)
+		return
+	}
+	n.out.write(append(b, '\n'))
+}
+
+func (n *ndjsonSink) runStart(cases int) {
+	n.emit(toolkit.RunEvent{Event: toolkit.EventRunStart, Cases: cases})
+}
+
+func (n *ndjsonSink) caseStart(ep toolkit.Endpoint, tc toolkit.Test) {
+	n.emit(toolkit.RunEvent{Event: toolkit.EventCaseStart, Endpoint: ep.Name, Method: ep.Method, TestID: tc.ID, As: tc.As})
+}
+
+func (n *ndjsonSink) caseFinish(res toolkit.UnittestCaseResult) {
+	n.emit(toolkit.RunEvent{Event: toolkit.EventCaseFinish, Result: &res})
+}
+
+func (n *ndjsonSink) runFinish(rep toolkit.UnittestReport) {
+	summary := rep.Summary
+	n.emit(toolkit.RunEvent{Event: toolkit.EventRunSummary, Summary: &summary, Interrupted: rep.Interrupted})
+}
+
+// tapSink writes TAP version 13. The plan comes first because the number of
+// cases is known before the first one runs.
+type tapSink struct {
+	out *streamWriter
+	n   int
+}
+
+// tapDiagnostic is the YAML block under a failing or flaky test point.
+type tapDiagnostic struct {
+	Outcome        string `yaml:"outcome"`
+	Failure        string `yaml:"failure_type,omitempty"`
+	Message        string `yaml:"message,omitempty"`
+	Error          string `yaml:"error,omitempty"`
+	Status         int    `yaml:"status,omitempty"`
+	ExpectedStatus []int  `yaml:"expected_status,omitempty,flow"`
+	LatencyMS      int64  `yaml:"latency_ms"`
+	Attempts       int    `yaml:"attempts,omitempty"`
+}
+
+func (t *tapSink) runStart(cases int) {
+	t.out.write([]byte(fmt.Sprintf("TAP version 13\n1..%d\n", cases)))
+}
+
+func (t *tapSink) caseStart(toolkit.Endpoint, toolkit.Test) {}
+
+func (t *tapSink) caseFinish(res toolkit.UnittestCaseResult) {
+	t.n++
+	var buf bytes.Buffer
+	desc := tapEscape(fmt.Sprintf("%s %s %s", res.Method, res.Endpoint, res.TestID))
+	switch res.Outcome {
+	case toolkit.OutcomePassed:
+		fmt.Fprintf(&buf, "ok %d - %s\n", t.n, desc)
+	case toolkit.OutcomeSkipped:
+		fmt.Fprintf(&buf, "ok %d - %s # SKIP %s\n", t.n, desc, tapEscape(res.Why))
+	default:
+		status := "not ok"
+		if res.Outcome == toolkit.OutcomeFlaky {
+			status = "ok"
+		}
+		fmt.Fprintf(&buf, "%s %d - %s\n", status, t.n, desc)
+		writeTAPDiagnostic(&buf, tapDiagnostic{
+			Outcome:        res.Outcome,
+			Failure:        res.Failure,
+			Message:        res.Why,
+			Error:          res.Error,
+			Status:         res.Status,
+			ExpectedStatus: res.ExpectedStatus,
+			LatencyMS:      res.LatencyMS,
+			Attempts:       len(res.Attempts),
+		})
+	}
+	t.out.write(buf.Bytes())
+}
+
+func (t *tapSink) runFinish(rep toolkit.UnittestReport) {
+	s := rep.Summary
+	line := fmt.Sprintf("# total %d, passed %d, flaky %d, failed %d, errored %d, skipped %d, hook failures %d\n",
+		s.Total, s.Passed, s.Flaky, s.Failed, s.Errored, s.Skipped, s.HookFailures)
+	if rep.Interrupted {
+		line += "# run interrupted, remaining cases skipped\n"
+	}
+	t.out.write([]byte(line))
+}
+
+func writeTAPDiagnostic(buf *bytes.Buffer, d tapDiagnostic) {
+	raw, err := yaml.Marshal(d)
+	if err != nil {
+		slog.Error("tester.stream: marshal tap diagnostic failed", "error", err)
+		return
+	}
+	buf.WriteString("  ---\n")
+	for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+		buf.WriteString("  " + line + "\n")
+	}
+	buf.WriteString("  ...\n")
+}
+
+// tapEscape keeps a description on one line and stops `#` from starting a
+// directive.
+func tapEscape(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.NewReplacer(`\`, `\\`, "#", `\#`).Replace(s)
+}
@@ -0,0 +1,143 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"synrax/toolkit"
+)
+
+func TestTAPStream(t *testing.T) {
+	var out bytes.Buffer
+	sink := newEventSink(&Stream{Format: StreamTAP, Writer: &out})
+	sink.runStart(4)
+	sink.caseFinish(toolkit.UnittestCaseResult{Endpoint: "/items", Method: "GET", TestID: "list", Outcome: toolkit.OutcomePassed})
+	sink.caseFinish(toolkit.UnittestCaseResult{
+		Endpoint: "/items", Method: "POST", TestID: "create #1", Outcome: toolkit.OutcomeFailed,
+		Failure: "status_mismatch", Why: "Expected status in [201] but received 500.", Status: 500,
+		ExpectedStatus: []int{201}, LatencyMS: 12,
+	})
+	sink.caseFinish(toolkit.UnittestCaseResult{
+		Endpoint: "/items", Method: "GET", TestID: "get", Outcome: toolkit.OutcomeFlaky, Status: 200, LatencyMS: 8,
+		Attempts: []toolkit.CaseAttempt{{Status: 503}, {Status: 200}},
+	})
+	sink.caseFinish(skippedResult(toolkit.Endpoint{Name: "/items", Method: "DELETE"}, toolkit.Test{ID: "remove"}))
+	sink.runFinish(toolkit.UnittestReport{
+		Summary:     toolkit.UnittestSummary{Total: 4, Passed: 1, Failed: 1, Flaky: 1, Skipped: 1},
+		Interrupted: true,
+	})
+
+	want := `TAP version 13
+1..4
+ok 1 - GET /items list
+not ok 2 - POST /items create \#1
+  ---
+  outcome: failed
+  failure_type: status_mismatch
+  message: Expected status in [201] but received 500.
+  status: 500
+  expected_status: [201]
+  latency_ms: 12
+  ...
+ok 3 - GET /items get
+  ---
+  outcome: flaky
+  status: 200
+  latency_ms: 8
+  attempts: 2
+  ...
+ok 4 - DELETE /items remove # SKIP Run was interrupted before this case completed.
+# total 4, passed 1, flaky 1, failed 1, errored 0, skipped 1, hook failures 0
+# run interrupted, remaining cases skipped
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// failingWriter accepts ok writes and fails every later one.
+type failingWriter struct {
+	ok     int
+	writes int
+	buf    bytes.Buffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > w.ok {
+		return 0, errors.New("broken pipe")
+	}
+	return w.buf.Write(p)
+}
+
+func TestNDJSONStreamDropsEventsAfterWriteError(t *testing.T) {
+	w := &failingWriter{ok: 1}
+	sink := newEventSink(&Stream{Format: StreamNDJSON, Writer: w})
+	sink.runStart(2)
+	sink.caseStart(toolkit.Endpoint{Name: "/items", Method: "GET"}, toolkit.Test{ID: "list"})
+	sink.caseFinish(toolkit.UnittestCaseResult{TestID: "list", Outcome: toolkit.OutcomePassed})
+	sink.runFinish(toolkit.UnittestReport{})
+
+	if w.writes != 2 {
+		t.Errorf("%d writes, want 2: events after the failed one must be dropped", w.writes)
+	}
+	var ev toolkit.RunEvent
+	if err := json.Unmarshal(w.buf.Bytes(), &ev); err != nil || ev.Event != toolkit.EventRunStart || ev.Seq != 1 || ev.Cases != 2 {
+		t.Errorf("first event %+v, err %v", ev, err)
+	}
+}
+
+func TestNDJSONStreamRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	spec := toolkit.TestSpec{BaseURL: srv.URL, Endpoints: []toolkit.Endpoint{{
+		Name: "/items", Method: "GET", Tests: []toolkit.Test{{ID: "a"}, {ID: "b", Expectation: toolkit.Expectation{Status: []int{404}}}},
+	}}}
+
+	var out bytes.Buffer
+	report, err := Run(context.Background(), spec, toolkit.UnittestConfig{}, Options{Stream: &Stream{Format: StreamNDJSON, Writer: &out}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []toolkit.RunEvent
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var ev toolkit.RunEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	wantEvents := []string{
+		toolkit.EventRunStart,
+		toolkit.EventCaseStart, toolkit.EventCaseFinish,
+		toolkit.EventCaseStart, toolkit.EventCaseFinish,
+		toolkit.EventRunSummary,
+	}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("%d events, want %d", len(events), len(wantEvents))
+	}
+	for i, ev := range events {
+		if ev.Event != wantEvents[i] || ev.Seq != i+1 {
+			t.Errorf("event %d: %s seq %d, want %s seq %d", i, ev.Event, ev.Seq, wantEvents[i], i+1)
+		}
+	}
+	if events[0].Cases != 2 {
+		t.Errorf("run_start cases %d, want 2", events[0].Cases)
+	}
+	if r := events[4].Result; r == nil || r.TestID != "b" || r.Outcome != toolkit.OutcomeFailed {
+		t.Errorf("second case_finish %+v", r)
+	}
+	if s := events[5].Summary; s == nil || *s != report.Summary {
+		t.Errorf("run_summary %+v, want %+v", s, report.Summary)
+	}
+}
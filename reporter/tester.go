@@ -2,59 +2,307 @@ package reporter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
 	"synrax/toolkit"
 )
 
-func Run(spec toolkit.TestSpec, cfg toolkit.UnittestConfig) toolkit.UnittestReport {
-	client := &http.Client{Timeout: 15 * time.Second}
+// runState is shared by every request of a single Run.
+type runState struct {
+	client  *http.Client
+	baseURL string
+	cfg     toolkit.UnittestConfig
+	quota   *quotaTracker
+	auth    *authCache
+	jars    *cookieJars
+}
+
+// httpResult is what executeRequest observed for a single request.
+type httpResult struct {
+	Status    int
+	Header    http.Header
+	Body      string
+	LatencyMS int64
+}
+
+// plannedEndpoint is an endpoint with the cases a run will execute, after
+// authz matrix expansion and filtering.
+type plannedEndpoint struct {
+	ep     toolkit.Endpoint
+	tests  []toolkit.Test
+	errors []toolkit.UnittestCaseResult // cases that could not be derived
+}
+
+func planRun(spec toolkit.TestSpec, cfg toolkit.UnittestConfig, sel *selector) []plannedEndpoint {
+	var plan []plannedEndpoint
+	deselected := 0
+	for _, ep := range spec.Endpoints {
+		if !sel.matchesEndpoint(ep) {
+			deselected += len(ep.Tests)
+			continue
+		}
+		p := plannedEndpoint{ep: ep}
+		tests := ep.Tests
+		if cfg.AuthzMatrix {
+			extra, err := authzMatrixTests(ep, cfg)
+			if err != nil {
+				slog.Warn("tester.plan: authz matrix skipped", "endpoint", ep.Name, "error", err)
+				p.errors = append(p.errors, toolkit.UnittestCaseResult{
+					Endpoint: ep.Name,
+					Method:   ep.Method,
+					TestID:   "authz-matrix",
+					Failure:  "request_build_error",
+					Why:      "Failed to derive authorization matrix cases for this endpoint.",
+					Error:    err.Error(),
+				})
+			}
+			tests = append(append([]toolkit.Test(nil), ep.Tests...), extra...)
+		}
+		for _, tc := range tests {
+			if sel.matches(ep, tc) {
+				p.tests = append(p.tests, tc)
+			} else {
+				deselected++
+			}
+		}
+		if len(p.tests) > 0 || len(p.errors) > 0 {
+			plan = append(plan, p)
+		}
+	}
+	if deselected > 0 {
+		slog.Info("tester.plan: filter deselected", "cases", deselected)
+	}
+	return plan
+}
+
+// Run executes the cases of the spec selected by opts.Filter. When ctx is
+// cancelled the in-flight request is aborted, the remaining cases are
+// reported as skipped and the partial report is returned with Interrupted
+// set. Teardown hooks of suites and endpoints that already started still run.
+func Run(ctx context.Context, spec toolkit.TestSpec, cfg toolkit.UnittestConfig, opts Options) (toolkit.UnittestReport, error) {
 	var rep toolkit.UnittestReport
+	startedAt := time.Now()
+	rep.StartedAt = startedAt.UTC()
 	baseURL := spec.BaseURL
 	if cfg.BaseURL != "" {
 		baseURL = cfg.BaseURL
 	}
-	log.Printf("tester.run: start base_url=%s endpoints=%d", baseURL, len(spec.Endpoints))
+	sel, err := opts.Filter.compile()
+	if err != nil {
+		slog.Error("tester.run: invalid filter", "error", err)
+		return rep, fmt.Errorf("%w: %v", ErrConfig, err)
+	}
+	rep.Filtered = !sel.all()
+	client, err := newTargetClient(cfg)
+	if err != nil {
+		slog.Error("tester.run: client setup failed", "error", err)
+		return rep, fmt.Errorf("%w: %v", ErrConfig, err)
+	}
+	authClient, err := newAuthClient(cfg)
+	if err != nil {
+		slog.Error("tester.run: client setup failed", "error", err)
+		return rep, fmt.Errorf("%w: %v", ErrConfig, err)
+	}
+	st := &runState{
+		client:  client,
+		baseURL: baseURL,
+		cfg:     cfg,
+		quota:   newQuotaTracker(),
+		auth:    newAuthCache(authClient),
+		jars:    newCookieJars(cfg.CookieJar, client),
+	}
+	teardownCtx := context.WithoutCancel(ctx)
+	plan := planRun(spec, cfg, sel)
+	slog.Info("tester.run: start", "base_url", baseURL, "endpoints", len(spec.Endpoints), "selected_endpoints", len(plan))
+	events := newEventSink(opts.Stream)
+	planned := 0
+	for _, p := range plan {
+		planned += len(p.errors) + len(p.tests)
+	}
+	events.runStart(planned)
+	defer func() { events.runFinish(rep) }()
+	finish := func(res toolkit.UnittestCaseResult) {
+		tally(&rep, res)
+		events.caseFinish(rep.Results[len(rep.Results)-1])
+	}
+	if len(plan) == 0 {
+		slog.Warn("tester.run: nothing selected")
+		rep.DurationMS = time.Since(startedAt).Milliseconds()
+		return rep, nil
+	}
+
+	// A failed before_* hook errors the cases it wraps without sending their
+	// requests. An after_* hook runs whenever its before_* stage ran, so
+	// teardown still follows a failed or cancelled setup.
+	started := ctx.Err() == nil
+	var suiteSetup *toolkit.UnittestHookResult
+	if started {
+		hooks := runHooks(ctx, st, hookBeforeAll, hookScopeSuite, spec.BeforeAll, "")
+		recordHooks(&rep, hooks)
+		suiteSetup = firstFailedHook(hooks)
+	}
+
+	for _, p := range plan {
+		ep := p.ep
+		slog.Info("tester.run: endpoint", "endpoint", ep.Name, "method", ep.Method, "tests", len(p.tests))
+		for _, res := range p.errors {
+			finish(res)
+		}
 
-	for _, ep := range spec.Endpoints {
-		log.Printf("tester.run: endpoint name=%s method=%s tests=%d", ep.Name, ep.Method, len(ep.Tests))
-		for _, tc := range ep.Tests {
-			rep.Summary.Total++
-			log.Printf("tester.run: case start endpoint=%s test_id=%s", ep.Name, tc.ID)
-			res := runOne(client, baseURL, ep, tc, cfg)
-			rep.Results = append(rep.Results, res)
-			if res.Passed {
-				rep.Summary.Passed++
-			} else {
-				rep.Summary.Failed++
+		if ctx.Err() != nil || len(p.tests) == 0 {
+			for _, tc := range p.tests {
+				finish(skippedResult(ep, tc))
 			}
-			log.Printf("tester.run: case done endpoint=%s test_id=%s passed=%t status=%d failure=%s", ep.Name, tc.ID, res.Passed, res.Status, res.Failure)
+			continue
 		}
+		if suiteSetup != nil {
+			for _, tc := range p.tests {
+				finish(hookFailedResult(ep, tc, *suiteSetup))
+			}
+			continue
+		}
+
+		epHooks := runHooks(ctx, st, hookBeforeAll, ep.Name, ep.BeforeAll, "")
+		recordHooks(&rep, epHooks)
+		epSetup := firstFailedHook(epHooks)
+		for _, tc := range p.tests {
+			if ctx.Err() != nil {
+				finish(skippedResult(ep, tc))
+				continue
+			}
+			if epSetup != nil {
+				finish(hookFailedResult(ep, tc, *epSetup))
+				continue
+			}
+			// every record logged while the case runs carries the case attributes
+			caseAttrs := []slog.Attr{slog.String("endpoint", ep.Name), slog.String("method", ep.Method), slog.String("test_id", tc.ID)}
+			caseCtx := toolkit.WithLogAttrs(ctx, caseAttrs...)
+			caseTeardownCtx := toolkit.WithLogAttrs(teardownCtx, caseAttrs...)
+			slog.DebugContext(caseCtx, "tester.run: case start")
+			events.caseStart(ep, tc)
+			hooks := runHooks(caseCtx, st, hookBeforeEach, hookScopeSuite, spec.BeforeEach, tc.ID)
+			recordHooks(&rep, hooks)
+			setup := firstFailedHook(hooks)
+			epEachRan := setup == nil
+			if epEachRan {
+				hooks = runHooks(caseCtx, st, hookBeforeEach, ep.Name, ep.BeforeEach, tc.ID)
+				recordHooks(&rep, hooks)
+				setup = firstFailedHook(hooks)
+			}
+			var res toolkit.UnittestCaseResult
+			switch {
+			case setup != nil && ctx.Err() != nil:
+				res = skippedResult(ep, tc) // the hook was aborted, not broken
+			case setup != nil:
+				res = hookFailedResult(ep, tc, *setup)
+			default:
+				res = runWithRetry(caseCtx, st, ep, tc)
+				if !res.Passed && ctx.Err() != nil {
+					res = skippedResult(ep, tc) // aborted mid-flight, the failure says nothing about the API
+				}
+			}
+			// teardown runs regardless of the case outcome
+			if epEachRan {
+				recordHooks(&rep, runHooks(caseTeardownCtx, st, hookAfterEach, ep.Name, ep.AfterEach, tc.ID))
+			}
+			recordHooks(&rep, runHooks(caseTeardownCtx, st, hookAfterEach, hookScopeSuite, spec.AfterEach, tc.ID))
+			finish(res)
+			slog.InfoContext(caseCtx, "tester.run: case done", "outcome", rep.Results[len(rep.Results)-1].Outcome, "status", res.Status, "failure", res.Failure, "latency_ms", res.LatencyMS)
+		}
+		recordHooks(&rep, runHooks(teardownCtx, st, hookAfterAll, ep.Name, ep.AfterAll, ""))
+	}
+
+	if started {
+		recordHooks(&rep, runHooks(teardownCtx, st, hookAfterAll, hookScopeSuite, spec.AfterAll, ""))
+	}
+	if ctx.Err() != nil {
+		rep.Interrupted = true
+		slog.Warn("tester.run: interrupted", "skipped", rep.Summary.Skipped, "error", context.Cause(ctx))
+	}
+	rep.DurationMS = time.Since(startedAt).Milliseconds()
+	slog.Info("tester.run: completed", "total", rep.Summary.Total, "passed", rep.Summary.Passed, "flaky", rep.Summary.Flaky, "failed", rep.Summary.Failed, "errored", rep.Summary.Errored, "skipped", rep.Summary.Skipped, "hook_failures", rep.Summary.HookFailures, "duration_ms", rep.DurationMS)
+	return rep, nil
+}
+
+// tally appends a case result and updates the summary counters.
+func tally(rep *toolkit.UnittestReport, res toolkit.UnittestCaseResult) {
+	if res.Outcome == "" {
+		res.Outcome = caseOutcome(res)
+	}
+	rep.Results = append(rep.Results, res)
+	rep.Summary.Total++
+	switch res.Outcome {
+	case toolkit.OutcomeSkipped:
+		rep.Summary.Skipped++
+	case toolkit.OutcomePassed:
+		rep.Summary.Passed++
+	case toolkit.OutcomeFlaky:
+		rep.Summary.Flaky++
+	case toolkit.OutcomeErrored:
+		rep.Summary.Errored++
+	default:
+		rep.Summary.Failed++
+	}
+}
+
+// caseOutcome separates broken tests from broken APIs: failures that happen
+// before anything reaches the target are errored, the rest failed.
+func caseOutcome(res toolkit.UnittestCaseResult) string {
+	if res.Passed {
+		return toolkit.OutcomePassed
+	}
+	switch res.Failure {
+	case "request_build_error", "auth_error", "hook_failed":
+		return toolkit.OutcomeErrored
+	default:
+		return toolkit.OutcomeFailed
+	}
+}
+
+func skippedResult(ep toolkit.Endpoint, tc toolkit.Test) toolkit.UnittestCaseResult {
+	return toolkit.UnittestCaseResult{
+		Endpoint:        ep.Name,
+		Method:          ep.Method,
+		TestID:          tc.ID,
+		As:              tc.As,
+		Outcome:         toolkit.OutcomeSkipped,
+		Why:             "Run was interrupted before this case completed.",
+		ExpectedStatus:  append([]int(nil), tc.Expectation.Status...),
+		ExpectedContent: tc.Expectation.Content,
 	}
-	log.Printf("tester.run: completed total=%d passed=%d failed=%d", rep.Summary.Total, rep.Summary.Passed, rep.Summary.Failed)
-	return rep
 }
 
-func runOne(client *http.Client, baseURL string, ep toolkit.Endpoint, tc toolkit.Test, cfg toolkit.UnittestConfig) toolkit.UnittestCaseResult {
+func runOne(ctx context.Context, st *runState, ep toolkit.Endpoint, tc toolkit.Test) toolkit.UnittestCaseResult {
 	cr := toolkit.UnittestCaseResult{
 		Endpoint:        ep.Name,
 		Method:          ep.Method,
 		TestID:          tc.ID,
+		As:              tc.As,
 		ExpectedStatus:  append([]int(nil), tc.Expectation.Status...),
 		ExpectedContent: tc.Expectation.Content,
 	}
 
-	fullURL, err := buildURL(baseURL, ep.Name, tc.Request.PathParams, tc.Request.Query)
+	if _, _, err := resolveAuth(st.cfg, tc.As); err != nil {
+		slog.WarnContext(ctx, "tester.run_one: identity failed", "error", err)
+		cr.Passed = false
+		cr.Failure = "request_build_error"
+		cr.Why = "Test selects an identity that is not configured."
+		cr.Error = err.Error()
+		return cr
+	}
+
+	fullURL, err := buildURL(st.baseURL, ep.Name, tc.Request.PathParams, tc.Request.Query)
 	if err != nil {
-		log.Printf("tester.run_one: build url failed endpoint=%s test_id=%s error=%v", ep.Name, tc.ID, err)
+		slog.WarnContext(ctx, "tester.run_one: build url failed", "error", err)
 		cr.Passed = false
 		cr.Failure = "request_build_error"
 		cr.Why = "Failed to build request URL for this test case."
@@ -62,53 +310,59 @@ func runOne(client *http.Client, baseURL string, ep toolkit.Endpoint, tc toolkit
 		return cr
 	}
 
-	if limit, ok := parseRateLimitTestID(tc.ID); ok {
-		if limit <= 0 {
-			limit = 1
-		}
-		for i := 0; i < limit+1; i++ {
-			status, raw, latency, runErr := executeRequest(client, ep, tc, cfg, fullURL)
-			cr.LatencyMS += latency
-			if runErr != nil {
-				log.Printf("tester.run_one: request failed endpoint=%s test_id=%s error=%v", ep.Name, tc.ID, runErr)
-				cr.Passed = false
-				cr.Failure = "transport_error"
-				cr.Why = "Request did not complete successfully."
-				cr.Error = runErr.Error()
-				return cr
-			}
-			cr.Status = status
-			cr.Body = raw
+	cr.Request = caseRequest(ep, tc, fullURL)
+
+	var header http.Header
+	expectStatus := tc.Expectation.Status
+	if rl, ok := rateLimitSpecFor(tc); ok {
+		// crossing the limit is the point of the test, not a 2xx
+		if len(expectStatus) == 0 {
+			expectStatus = []int{http.StatusTooManyRequests}
+			cr.ExpectedStatus = expectStatus
+		}
+		h, completed := runRateLimit(ctx, st, ep, tc, rl, fullURL, &cr)
+		if !completed {
+			return cr
 		}
+		header = h
 	} else {
-		status, raw, latency, runErr := executeRequest(client, ep, tc, cfg, fullURL)
-		cr.LatencyMS = latency
+		res, runErr := executeRequest(ctx, st, ep, tc, fullURL)
+		cr.LatencyMS = res.LatencyMS
 		if runErr != nil {
-			log.Printf("tester.run_one: request failed endpoint=%s test_id=%s error=%v", ep.Name, tc.ID, runErr)
-			cr.Passed = false
-			cr.Failure = "transport_error"
-			cr.Why = "Request did not complete successfully."
-			cr.Error = runErr.Error()
+			slog.DebugContext(ctx, "tester.run_one: request failed", "error", runErr)
+			markRequestFailure(&cr, runErr)
 			return cr
 		}
-		cr.Status = status
-		cr.Body = raw
+		cr.Status = res.Status
+		cr.Body = res.Body
+		header = res.Header
 	}
 
 	// ASSERT: status
-	if !statusMatches(cr.Status, tc.Expectation.Status) {
-		log.Printf("tester.run_one: status mismatch endpoint=%s test_id=%s got=%d expected=%v", ep.Name, tc.ID, cr.Status, tc.Expectation.Status)
+	if !statusMatches(cr.Status, expectStatus) {
+		slog.DebugContext(ctx, "tester.run_one: status mismatch", "got", cr.Status, "expected", expectStatus)
 		cr.Passed = false
 		cr.Failure = "status_mismatch"
-		cr.Why = buildStatusMismatchReason(tc.Expectation.Status, cr.Status, cr.Body)
-		cr.Error = fmt.Sprintf("status mismatch (got=%d expected=%v)", cr.Status, tc.Expectation.Status)
+		cr.Why = buildStatusMismatchReason(expectStatus, cr.Status, cr.Body)
+		cr.Error = fmt.Sprintf("status mismatch (got=%d expected=%v)", cr.Status, expectStatus)
 		return cr
 	}
 
+	if len(tc.Expectation.Cookies) > 0 {
+		if reason, mismatch := cookieMismatch(tc.Expectation.Cookies, header); mismatch {
+			slog.DebugContext(ctx, "tester.run_one: cookie mismatch", "reason", reason)
+			cr.Passed = false
+			cr.Failure = "cookie_mismatch"
+			cr.Why = "Set-Cookie did not match expectation: " + reason + "."
+			cr.Error = reason
+			return cr
+		}
+	}
+
 	if tc.Expectation.Content != nil {
 		var actual any
 		if err := json.Unmarshal([]byte(cr.Body), &actual); err != nil {
-			log.Printf("tester.run_one: response parse failed endpoint=%s test_id=%s error=%v", ep.Name, tc.ID, err)
+			slog.DebugContext(ctx, "tester.run_one: response parse failed", "error", err)
 			cr.Passed = false
 			cr.Failure = "response_parse_error"
 			cr.Why = "Expected structured content, but response body is not valid JSON."
@@ -116,10 +370,13 @@ func runOne(client *http.Client, baseURL string, ep toolkit.Endpoint, tc toolkit
 			return cr
 		}
 		if !contentMatches(actual, tc.Expectation.Content, isSuccessTest(tc.ID)) {
-			log.Printf("tester.run_one: content mismatch endpoint=%s test_id=%s", ep.Name, tc.ID)
+			slog.DebugContext(ctx, "tester.run_one: content mismatch")
 			cr.Passed = false
 			cr.Failure = "content_mismatch"
-			cr.Why = buildContentMismatchReason(tc.Expectation.Content, actual)
+			if path, exp, act, ok := firstContentDifference("$", tc.Expectation.Content, actual); ok {
+				cr.ContentDiff = &toolkit.ContentDiff{Path: path, Expected: exp, Actual: act}
+			}
+			cr.Why = toolkit.ContentMismatchReason(cr.ContentDiff)
 			cr.Error = "response content mismatch"
 			return cr
 		}
@@ -129,13 +386,8 @@ func runOne(client *http.Client, baseURL string, ep toolkit.Endpoint, tc toolkit
 	return cr
 }
 
-func executeRequest(client *http.Client, ep toolkit.Endpoint, tc toolkit.Test, cfg toolkit.UnittestConfig, fullURL string) (int, string, int64, error) {
+func executeRequest(parent context.Context, st *runState, ep toolkit.Endpoint, tc toolkit.Test, fullURL string) (httpResult, error) {
 	headers := cloneHeaders(tc.Request.Headers)
-	if shouldInjectAuth(tc.ID, cfg.AuthToken) {
-		if _, ok := headers["Authorization"]; !ok {
-			headers["Authorization"] = "Bearer " + cfg.AuthToken
-		}
-	}
 	headers["X-Unittest-Case"] = tc.ID
 
 	var body io.Reader
@@ -149,33 +401,100 @@ func executeRequest(client *http.Client, ep toolkit.Endpoint, tc toolkit.Test, c
 		}
 	}
 
-	req, err := http.NewRequest(ep.Method, fullURL, body)
+	ctx, cancel := withTimeouts(parent, resolveTimeouts(st.cfg.Timeouts, ep.Timeouts, tc.Timeouts))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, ep.Method, fullURL, body)
 	if err != nil {
-		return 0, "", 0, fmt.Errorf("NewRequest: %w", err)
+		return httpResult{}, fmt.Errorf("NewRequest: %w", err)
 	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
+	// the token fetch gets the run context, not the budget of this request
+	if err := applyAuth(parent, st, req, tc); err != nil {
+		return httpResult{}, err
+	}
+	identity, _, _ := resolveAuth(st.cfg, tc.As)
+	client := st.jars.client(identity)
+	applyCSRF(st, client, req, tc)
+
+	// every request counts against the server side quota, including hooks
+	st.quota.record(quotaKey(st, tc))
 
 	start := time.Now()
-	log.Printf("tester.execute: sending method=%s url=%s test_id=%s", ep.Method, fullURL, tc.ID)
+	slog.DebugContext(ctx, "tester.execute: sending", "request", ep.Method+" "+fullURL)
 	resp, err := client.Do(req)
 	latency := time.Since(start).Milliseconds()
 	if err != nil {
-		return 0, "", latency, fmt.Errorf("Do: %w", err)
+		if te, ok := timeoutCause(ctx); ok {
+			err = te
+		}
+		return httpResult{LatencyMS: latency}, fmt.Errorf("Do: %w", err)
 	}
 	defer resp.Body.Close()
 
-	raw, _ := io.ReadAll(resp.Body)
-	log.Printf("tester.execute: received method=%s url=%s test_id=%s status=%d latency_ms=%d", ep.Method, fullURL, tc.ID, resp.StatusCode, latency)
-	return resp.StatusCode, string(raw), latency, nil
+	raw, err := io.ReadAll(resp.Body)
+	if te, ok := timeoutCause(ctx); ok && err != nil {
+		return httpResult{Status: resp.StatusCode, LatencyMS: time.Since(start).Milliseconds()}, fmt.Errorf("read body: %w", te)
+	}
+	slog.DebugContext(ctx, "tester.execute: received", "request", ep.Method+" "+fullURL, "status", resp.StatusCode, "latency_ms", latency)
+	return httpResult{Status: resp.StatusCode, Header: resp.Header, Body: string(raw), LatencyMS: latency}, nil
 }
 
-func shouldInjectAuth(testID string, token string) bool {
-	if token == "" {
-		return false
+// markRequestFailure records an error returned by executeRequest.
+func markRequestFailure(cr *toolkit.UnittestCaseResult, err error) {
+	cr.Passed = false
+	cr.Failure, cr.Why = classifyRequestError(err)
+	cr.Error = err.Error()
+	var te *timeoutError
+	if errors.As(err, &te) {
+		cr.TimeoutPhase = te.phase
 	}
+}
 
+func classifyRequestError(err error) (string, string) {
+	var te *timeoutError
+	switch {
+	case errors.As(err, &te):
+		return "timeout", fmt.Sprintf("Request exceeded its %s timeout budget.", te.phase)
+	case errors.Is(err, errAuth):
+		return "auth_error", "Could not obtain credentials for this request."
+	case isTLSError(err):
+		return "tls_error", "TLS handshake with the target failed."
+	default:
+		return "transport_error", "Request did not complete successfully."
+	}
+}
+
+// sensitiveHeaders are masked in reports.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+}
+
+// caseRequest describes the request of a case for the report. Only what the
+// spec defines is listed; credentials added by auth providers never are.
+func caseRequest(ep toolkit.Endpoint, tc toolkit.Test, fullURL string) *toolkit.CaseRequest {
+	req := &toolkit.CaseRequest{Method: ep.Method, URL: fullURL}
+	if len(tc.Request.Headers) > 0 {
+		req.Headers = make(map[string]string, len(tc.Request.Headers))
+		for k, v := range tc.Request.Headers {
+			if sensitiveHeaders[strings.ToLower(k)] && v != "" {
+				v = "<redacted>"
+			}
+			req.Headers[k] = v
+		}
+	}
+	if ep.Method != "GET" && ep.Method != "DELETE" && len(tc.Request.BodyJson) > 0 {
+		req.Body = tc.Request.BodyJson
+	}
+	return req
+}
+
+func shouldInjectAuth(testID string) bool {
 	id := strings.ToLower(strings.TrimSpace(testID))
 	if strings.Contains(id, "missing-auth") || strings.Contains(id, "missing_auth") {
 		return false
@@ -231,19 +550,6 @@ func statusMatches(got int, allowed []int) bool {
 	return false
 }
 
-func parseRateLimitTestID(testID string) (int, bool) {
-	id := strings.ToLower(strings.TrimSpace(testID))
-	const prefix = "rate-limit-exceeded-"
-	if !strings.HasPrefix(id, prefix) {
-		return 0, false
-	}
-	n, err := strconv.Atoi(strings.TrimPrefix(id, prefix))
-	if err != nil {
-		return 0, false
-	}
-	return n, true
-}
-
 func contentMatches(actual any, expected any, relaxNumbers bool) bool {
 	switch exp := expected.(type) {
 	case map[string]any:
@@ -329,13 +635,6 @@ func buildStatusMismatchReason(expected []int, got int, rawBody string) string {
 	return base
 }
 
-func buildContentMismatchReason(expected any, actual any) string {
-	if path, exp, act, ok := firstContentDifference("$", expected, actual); ok {
-		return fmt.Sprintf("Response content mismatch at %s (expected=%s got=%s).", path, exp, act)
-	}
-	return "Response content did not match expected structure."
-}
-
 func firstContentDifference(path string, expected any, actual any) (string, string, string, bool) {
 	switch exp := expected.(type) {
 	case map[string]any:
@@ -0,0 +1,125 @@
+package reporter
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"synrax/toolkit"
+)
+
+func TestClassifyRequestError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantFailure string
+		wantOutcome string
+	}{
+		{"timeout", fmt.Errorf("Do: %w", &timeoutError{phase: timeoutPhaseTotal, budget: time.Second}), "timeout", toolkit.OutcomeFailed},
+		{"auth", fmt.Errorf("%w: token endpoint down", errAuth), "auth_error", toolkit.OutcomeErrored},
+		{"certificate", fmt.Errorf("Do: %w", x509.UnknownAuthorityError{}), "tls_error", toolkit.OutcomeFailed},
+		{"tls alert", errors.New("Do: remote error: tls: bad certificate"), "tls_error", toolkit.OutcomeFailed},
+		{"refused", errors.New("Do: dial tcp 127.0.0.1:1: connect: connection refused"), "transport_error", toolkit.OutcomeFailed},
+	}
+	for _, tt := range tests {
+		var cr toolkit.UnittestCaseResult
+		markRequestFailure(&cr, tt.err)
+		if cr.Failure != tt.wantFailure || cr.Error != tt.err.Error() || cr.Why == "" {
+			t.Errorf("%s: failure %q error %q why %q, want %q", tt.name, cr.Failure, cr.Error, cr.Why, tt.wantFailure)
+		}
+		if got := caseOutcome(cr); got != tt.wantOutcome {
+			t.Errorf("%s: outcome %q, want %q", tt.name, got, tt.wantOutcome)
+		}
+	}
+}
+
+func TestCaseOutcome(t *testing.T) {
+	tests := []struct {
+		res  toolkit.UnittestCaseResult
+		want string
+	}{
+		{toolkit.UnittestCaseResult{Passed: true}, toolkit.OutcomePassed},
+		{toolkit.UnittestCaseResult{Failure: "status_mismatch"}, toolkit.OutcomeFailed},
+		{toolkit.UnittestCaseResult{}, toolkit.OutcomeFailed},
+		{toolkit.UnittestCaseResult{Failure: "request_build_error"}, toolkit.OutcomeErrored},
+		{toolkit.UnittestCaseResult{Failure: "auth_error"}, toolkit.OutcomeErrored},
+		{toolkit.UnittestCaseResult{Failure: "hook_failed"}, toolkit.OutcomeErrored},
+	}
+	for _, tt := range tests {
+		if got := caseOutcome(tt.res); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.res, got, tt.want)
+		}
+	}
+}
+
+func TestTally(t *testing.T) {
+	var rep toolkit.UnittestReport
+	for _, res := range []toolkit.UnittestCaseResult{
+		{TestID: "a", Passed: true},
+		{TestID: "b", Failure: "status_mismatch"},
+		{TestID: "c", Failure: "auth_error"},
+		{TestID: "d", Outcome: toolkit.OutcomeSkipped},
+		{TestID: "e", Passed: true, Outcome: toolkit.OutcomeFlaky},
+		{TestID: "f", Failure: "timeout"},
+	} {
+		tally(&rep, res)
+	}
+	want := toolkit.UnittestSummary{Total: 6, Passed: 1, Failed: 2, Errored: 1, Skipped: 1, Flaky: 1}
+	if rep.Summary != want {
+		t.Errorf("summary %+v, want %+v", rep.Summary, want)
+	}
+	outcomes := ""
+	for _, r := range rep.Results {
+		outcomes += r.TestID + "=" + r.Outcome + " "
+	}
+	if outcomes != "a=passed b=failed c=errored d=skipped e=flaky f=failed " {
+		t.Errorf("outcomes %s", outcomes)
+	}
+}
+
+// Cases broken before reaching the target count as errored, the target's
+// answers as passed or failed.
+func TestRunOutcomes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	cfg := toolkit.UnittestConfig{Identities: map[string]toolkit.Identity{
+		"broken": {Auth: &toolkit.AuthConfig{Type: authClientCredentials, TokenURL: closed.URL}},
+	}}
+	spec := toolkit.TestSpec{BaseURL: srv.URL, Endpoints: []toolkit.Endpoint{{
+		Name: "/items/{id}", Method: "GET",
+		Tests: []toolkit.Test{
+			{ID: "not-found", Request: toolkit.RequestSpecs{PathParams: map[string]string{"id": "1"}}, Expectation: toolkit.Expectation{Status: []int{404}}},
+			{ID: "found", Request: toolkit.RequestSpecs{PathParams: map[string]string{"id": "1"}}, Expectation: toolkit.Expectation{Status: []int{200}}},
+			{ID: "unknown-identity", As: "nobody", Request: toolkit.RequestSpecs{PathParams: map[string]string{"id": "1"}}},
+			{ID: "token-down", As: "broken", Request: toolkit.RequestSpecs{PathParams: map[string]string{"id": "1"}}},
+		},
+	}}}
+	report, err := Run(context.Background(), spec, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"not-found":        toolkit.OutcomePassed,
+		"found":            toolkit.OutcomeFailed,
+		"unknown-identity": toolkit.OutcomeErrored,
+		"token-down":       toolkit.OutcomeErrored,
+	}
+	for _, r := range report.Results {
+		if r.Outcome != want[r.TestID] {
+			t.Errorf("%s: outcome %q (%s), want %q", r.TestID, r.Outcome, r.Failure, want[r.TestID])
+		}
+	}
+	if s := report.Summary; s.Total != 4 || s.Passed != 1 || s.Failed != 1 || s.Errored != 2 {
+		t.Errorf("summary %+v", s)
+	}
+}
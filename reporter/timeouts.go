@@ -0,0 +1,176 @@
+package reporter
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"synrax/toolkit"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultTLSTimeout     = 10 * time.Second
+	defaultTotalTimeout   = 15 * time.Second
+
+	timeoutPhaseConnect = "connect"
+	timeoutPhaseTLS     = "tls"
+	timeoutPhaseTotal   = "total"
+)
+
+type requestTimeouts struct {
+	connect time.Duration
+	tls     time.Duration
+	total   time.Duration
+}
+
+// resolveTimeouts merges the layers field by field; later layers win. A
+// connect or TLS budget no layer sets defaults to at most the total budget,
+// so a short total_ms is not reported as a connect timeout.
+func resolveTimeouts(layers ...*toolkit.Timeouts) requestTimeouts {
+	var out requestTimeouts
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		if l.ConnectMS > 0 {
+			out.connect = time.Duration(l.ConnectMS) * time.Millisecond
+		}
+		if l.TLSMS > 0 {
+			out.tls = time.Duration(l.TLSMS) * time.Millisecond
+		}
+		if l.TotalMS > 0 {
+			out.total = time.Duration(l.TotalMS) * time.Millisecond
+		}
+	}
+	if out.total == 0 {
+		out.total = defaultTotalTimeout
+	}
+	if out.connect == 0 {
+		out.connect = min(defaultConnectTimeout, out.total)
+	}
+	if out.tls == 0 {
+		out.tls = min(defaultTLSTimeout, out.total)
+	}
+	return out
+}
+
+// timeoutError is the cancel cause of a request whose budget expired.
+type timeoutError struct {
+	phase  string // budget that expired
+	budget time.Duration
+	stage  string // what the request was doing when it expired
+}
+
+func (e *timeoutError) Error() string {
+	if e.phase == timeoutPhaseTotal && e.stage != "" {
+		return fmt.Sprintf("%s timeout after %s while %s", e.phase, e.budget, e.stage)
+	}
+	return fmt.Sprintf("%s timeout after %s", e.phase, e.budget)
+}
+
+func (e *timeoutError) Timeout() bool { return true }
+
+// withTimeouts returns a request context that is cancelled with a
+// *timeoutError when any budget expires. Connect and TLS budgets are armed by
+// httptrace hooks, so reused connections never start them.
+func withTimeouts(parent context.Context, t requestTimeouts) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	var (
+		mu           sync.Mutex
+		stage        = "connecting"
+		connectTimer *time.Timer
+		tlsTimer     *time.Timer
+	)
+	setStage := func(s string) {
+		mu.Lock()
+		stage = s
+		mu.Unlock()
+	}
+	expire := func(phase string, budget time.Duration) func() {
+		return func() {
+			mu.Lock()
+			current := stage
+			mu.Unlock()
+			cancel(&timeoutError{phase: phase, budget: budget, stage: current})
+		}
+	}
+	startConnect := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if connectTimer == nil {
+			connectTimer = time.AfterFunc(t.connect, expire(timeoutPhaseConnect, t.connect))
+		}
+	}
+	stopConnect := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if connectTimer != nil {
+			connectTimer.Stop()
+		}
+	}
+
+	totalTimer := time.AfterFunc(t.total, expire(timeoutPhaseTotal, t.total))
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			setStage("resolving host")
+			startConnect()
+		},
+		ConnectStart: func(string, string) {
+			setStage("connecting")
+			startConnect()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				stopConnect()
+			}
+		},
+		TLSHandshakeStart: func() {
+			setStage("in TLS handshake")
+			mu.Lock()
+			tlsTimer = time.AfterFunc(t.tls, expire(timeoutPhaseTLS, t.tls))
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			if tlsTimer != nil {
+				tlsTimer.Stop()
+			}
+			mu.Unlock()
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			stopConnect()
+			setStage("sending request")
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			setStage("awaiting response")
+		},
+		GotFirstResponseByte: func() {
+			setStage("reading body")
+		},
+	}
+
+	stop := func() {
+		totalTimer.Stop()
+		mu.Lock()
+		if connectTimer != nil {
+			connectTimer.Stop()
+		}
+		if tlsTimer != nil {
+			tlsTimer.Stop()
+		}
+		mu.Unlock()
+		cancel(nil)
+	}
+	return httptrace.WithClientTrace(ctx, trace), stop
+}
+
+// timeoutCause returns the expired budget of a request context, if any.
+func timeoutCause(ctx context.Context) (*timeoutError, bool) {
+	te, ok := context.Cause(ctx).(*timeoutError)
+	return te, ok
+}
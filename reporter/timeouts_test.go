@@ -0,0 +1,39 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"synrax/toolkit"
+)
+
+func TestResolveTimeouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []*toolkit.Timeouts
+		want   requestTimeouts
+	}{
+		{"defaults", nil, requestTimeouts{connect: 10 * time.Second, tls: 10 * time.Second, total: 15 * time.Second}},
+		{"nil layers", []*toolkit.Timeouts{nil, nil}, requestTimeouts{connect: 10 * time.Second, tls: 10 * time.Second, total: 15 * time.Second}},
+		{
+			"later layer wins per field",
+			[]*toolkit.Timeouts{{ConnectMS: 1000, TotalMS: 20000}, {ConnectMS: 2000}, {TLSMS: 3000}},
+			requestTimeouts{connect: 2 * time.Second, tls: 3 * time.Second, total: 20 * time.Second},
+		},
+		{
+			"short total caps the defaults",
+			[]*toolkit.Timeouts{{TotalMS: 500}},
+			requestTimeouts{connect: 500 * time.Millisecond, tls: 500 * time.Millisecond, total: 500 * time.Millisecond},
+		},
+		{
+			"explicit connect kept",
+			[]*toolkit.Timeouts{{ConnectMS: 4000}, {TotalMS: 1000}},
+			requestTimeouts{connect: 4 * time.Second, tls: time.Second, total: time.Second},
+		},
+	}
+	for _, tt := range tests {
+		if got := resolveTimeouts(tt.layers...); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,18 +2,56 @@ package toolkit
 
 import (
 	"bytes"
+	"embed"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"sort"
 	"strings"
 	"text/template"
 )
 
+// templates are compiled into the binary, the CLI runs from the user's repo
+//
+//go:embed templates/*.tpl
+var templateFS embed.FS
+
+// maxMarkdownBody caps each request, expected and response body of a case so
+// one large response does not bury the others. The size of the whole report
+// is only capped by MarkdownWithin.
+const maxMarkdownBody = 2000
+
 type GlobalData struct {
-	Total  int
-	Passed int
-	Failed int
+	Total        int
+	Passed       int
+	Failed       int
+	Flaky        int
+	Errored      int
+	Skipped      int
+	HookFailures int
+	Fixed        int
+	Interrupted  bool
+	RerunOf      string
+	Baseline     *BaselineComparison
+}
+
+// AllPassed is true when nothing failed, errored or was skipped.
+func (g GlobalData) AllPassed() bool {
+	return g.Failed == 0 && g.Errored == 0 && g.Skipped == 0 && g.HookFailures == 0
+}
+
+// EndpointSummary is one row of the per-endpoint tables.
+type EndpointSummary struct {
+	Name    string
+	Method  string
+	Cases   int
+	Passed  int
+	Failed  int
+	Flaky   int
+	Errored int
+	Skipped int
+
+	MinMS, AvgMS, P50MS, P95MS, MaxMS int64
 }
 
 type EndpointData struct {
@@ -21,44 +59,165 @@ type EndpointData struct {
 	Passed         string
 	Method         string
 	TestID         string
+	As             string
+	Failure        string
+	Why            string
+	Error          string
+	Request        *CaseRequest
+	RequestBody    string
 	ExpectedStatus []int
 	Status         int
+	ExpectedBody   string
+	Diff           *ContentDiff
 	Body           string
+	LatencyMS      int64
+	Attempts       int
 }
 
 func ParseUnittest(resultPath string, report UnittestReport) error {
-	p, err := filepath.Abs("./toolkit/templates")
+	return WriteFileAtomic(resultPath, func(file io.Writer) error {
+		return WriteMarkdown(file, report)
+	})
+}
+
+// WriteMarkdown renders the full report: summary, endpoint table, failing
+// cases, hook failures and latencies.
+func WriteMarkdown(file io.Writer, report UnittestReport) error {
+	parts, err := renderMarkdown(report)
 	if err != nil {
 		return err
 	}
+	_, err = io.WriteString(file, parts.String())
+	return err
+}
 
-	file, err := os.Create(resultPath)
+// MarkdownWithin renders the report in at most limit bytes. When the full
+// report is longer, whole failing cases and hook failures are left out from
+// the end, so no <details> block or code fence is cut open, and a line saying
+// what is missing and pointing to `elsewhere` is appended.
+func MarkdownWithin(report UnittestReport, limit int, elsewhere string) (string, error) {
+	parts, err := renderMarkdown(report)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if full := parts.String(); len(full) <= limit {
+		return full, nil
 	}
 
-	// case: There are no failed tests, so no explanation is needed
-	if report.Summary.Failed == 0 {
-		fmt.Println(report.Summary.Failed)
-		statement := []byte("All API Endpoints Passed.")
-		if _, err := file.Write(statement); err != nil {
-			return err
+	note := func(cases, hooks int) string {
+		return fmt.Sprintf("\n\n_Report truncated: %d of %d failing cases and %d of %d hook failures shown, the full report is in %s._\n",
+			cases, len(parts.failures), hooks, len(parts.hooks), elsewhere)
+	}
+	// shown counts never have more digits than the totals
+	budget := limit - len(note(len(parts.failures), len(parts.hooks)))
+
+	var b strings.Builder
+	head := parts.head
+	if len(head) > budget {
+		head = truncateUTF8(head, budget)
+		head = head[:strings.LastIndex(head, "\n")+1] // tables and lists end at a newline
+	}
+	b.WriteString(head)
+	latency := parts.latency
+	if b.Len()+len(latency) > budget {
+		latency = ""
+	}
+	budget -= len(latency)
+
+	cases := appendWithin(&b, failingCasesHeading, parts.failures, budget)
+	hooks := appendWithin(&b, hookFailuresHeading, parts.hooks, budget)
+	b.WriteString(latency)
+	b.WriteString(note(cases, hooks))
+	return b.String(), nil
+}
+
+// appendWithin writes heading and as many leading chunks as fit in budget,
+// and returns how many it wrote.
+func appendWithin(b *strings.Builder, heading string, chunks []string, budget int) int {
+	if len(chunks) == 0 || b.Len()+len(heading)+len(chunks[0]) > budget {
+		return 0
+	}
+	b.WriteString(heading)
+	n := 0
+	for _, c := range chunks {
+		if b.Len()+len(c) > budget {
+			break
 		}
-		return nil
+		b.WriteString(c)
+		n++
 	}
+	return n
+}
 
-	if err := writeGlobalData(p, file, report); err != nil {
-		return err
+const (
+	failingCasesHeading = "\n## Failing cases\n"
+	hookFailuresHeading = "\n## Hook failures\n"
+)
+
+// markdownParts is the rendered report split at the points where it can be
+// cut without breaking the Markdown.
+type markdownParts struct {
+	head     string   // summary and endpoint table
+	failures []string // one <details> block per failing case
+	hooks    []string // one <details> block per failed hook
+	latency  string
+}
+
+func (p markdownParts) String() string {
+	var b strings.Builder
+	b.WriteString(p.head)
+	if len(p.failures) > 0 {
+		b.WriteString(failingCasesHeading)
+		for _, f := range p.failures {
+			b.WriteString(f)
+		}
 	}
-	if err := writeEndpointFailure(p, file, report); err != nil {
-		return err
+	if len(p.hooks) > 0 {
+		b.WriteString(hookFailuresHeading)
+		for _, h := range p.hooks {
+			b.WriteString(h)
+		}
 	}
+	b.WriteString(p.latency)
+	return b.String()
+}
 
-	return nil
+func renderMarkdown(report UnittestReport) (markdownParts, error) {
+	var parts markdownParts
+	endpoints := summarizeEndpoints(report.Results)
+
+	var head bytes.Buffer
+	if err := writeGlobalData(&head, report); err != nil {
+		return parts, err
+	}
+	if err := writeEndpointSummary(&head, endpoints); err != nil {
+		return parts, err
+	}
+	parts.head = head.String()
+
+	var err error
+	if parts.failures, err = renderEndpointFailures(report); err != nil {
+		return parts, err
+	}
+	if parts.hooks, err = renderHookFailures(report); err != nil {
+		return parts, err
+	}
+	var latency bytes.Buffer
+	if err := writeLatency(&latency, endpoints); err != nil {
+		return parts, err
+	}
+	parts.latency = latency.String()
+	return parts, nil
 }
 
-func writeGlobalData(path string, file *os.File, report UnittestReport) error {
-	global_tmp, err := template.ParseFiles(path + "/global.tpl")
+func parseTemplate(name string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"cell": markdownCell,
+	}).ParseFS(templateFS, "templates/"+name)
+}
+
+func writeGlobalData(file io.Writer, report UnittestReport) error {
+	global_tmp, err := parseTemplate("global.tpl")
 	if err != nil {
 		return err
 	}
@@ -67,6 +226,15 @@ func writeGlobalData(path string, file *os.File, report UnittestReport) error {
 		Total:  report.Summary.Total,
 		Passed: report.Summary.Passed,
 		Failed: report.Summary.Failed,
+
+		Flaky:        report.Summary.Flaky,
+		Errored:      report.Summary.Errored,
+		Skipped:      report.Summary.Skipped,
+		HookFailures: report.Summary.HookFailures,
+		Fixed:        report.Summary.Fixed,
+		Interrupted:  report.Interrupted,
+		RerunOf:      report.RerunOf,
+		Baseline:     report.Baseline,
 	}
 
 	if err := global_tmp.Execute(file, globalData); err != nil {
@@ -75,37 +243,182 @@ func writeGlobalData(path string, file *os.File, report UnittestReport) error {
 	return nil
 }
 
-func writeEndpointFailure(path string, file *os.File, report UnittestReport) error {
-
-	endpoint_tmp, err := template.ParseFiles(path + "/endpoint.tpl")
+func writeEndpointSummary(file io.Writer, endpoints []EndpointSummary) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	endpoints_tmp, err := parseTemplate("endpoints.tpl")
 	if err != nil {
 		return err
 	}
+	return endpoints_tmp.Execute(file, endpoints)
+}
 
+func renderEndpointFailures(report UnittestReport) ([]string, error) {
 	var failures []EndpointData
 	for _, endpoint := range report.Results {
-		if endpoint.Passed {
+		if endpoint.Outcome != OutcomeFailed && endpoint.Outcome != OutcomeErrored {
 			continue
 		}
+		passed := "Failed"
+		if endpoint.Outcome == OutcomeErrored {
+			passed = "Errored"
+		}
 
-		failures = append(failures, EndpointData{
+		data := EndpointData{
 			Name:           endpoint.Endpoint,
-			Passed:         "False",
+			Passed:         passed,
 			Method:         endpoint.Method,
 			TestID:         endpoint.TestID,
+			As:             endpoint.As,
+			Failure:        endpoint.Failure,
+			Why:            endpoint.Why,
+			Error:          endpoint.Error,
+			Request:        endpoint.Request,
 			ExpectedStatus: endpoint.ExpectedStatus,
 			Status:         endpoint.Status,
-			Body:           formatEndpointBody(endpoint.Body),
-		})
+			Diff:           endpoint.ContentDiff,
+			LatencyMS:      endpoint.LatencyMS,
+			Attempts:       len(endpoint.Attempts),
+		}
+		if endpoint.Request != nil && len(endpoint.Request.Body) > 0 {
+			data.RequestBody = formatEndpointValue(endpoint.Request.Body)
+		}
+		if endpoint.ExpectedContent != nil {
+			data.ExpectedBody = formatEndpointValue(endpoint.ExpectedContent)
+		}
+		if endpoint.Status != 0 {
+			data.Body = formatEndpointBody(endpoint.Body)
+		}
+		failures = append(failures, data)
+	}
+	if len(failures) == 0 {
+		return nil, nil
 	}
 
+	endpoint_tmp, err := parseTemplate("endpoint.tpl")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(failures))
 	for _, endpointData := range failures {
-		if err := endpoint_tmp.Execute(file, endpointData); err != nil {
-			return err
+		var buf bytes.Buffer
+		if err := endpoint_tmp.Execute(&buf, endpointData); err != nil {
+			return nil, err
 		}
+		out = append(out, buf.String())
 	}
 
-	return nil
+	return out, nil
+}
+
+func renderHookFailures(report UnittestReport) ([]string, error) {
+	if report.Summary.HookFailures == 0 {
+		return nil, nil
+	}
+
+	hook_tmp, err := parseTemplate("hook.tpl")
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, hook := range report.Hooks {
+		if hook.Passed {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := hook_tmp.Execute(&buf, hook); err != nil {
+			return nil, err
+		}
+		out = append(out, buf.String())
+	}
+
+	return out, nil
+}
+
+func writeLatency(file io.Writer, endpoints []EndpointSummary) error {
+	var measured []EndpointSummary
+	for _, ep := range endpoints {
+		if ep.Cases > ep.Skipped+ep.Errored {
+			measured = append(measured, ep)
+		}
+	}
+	if len(measured) == 0 {
+		return nil
+	}
+	latency_tmp, err := parseTemplate("latency.tpl")
+	if err != nil {
+		return err
+	}
+	return latency_tmp.Execute(file, measured)
+}
+
+// summarizeEndpoints groups results per endpoint and method in report order.
+// Latencies cover cases that reached the target only.
+func summarizeEndpoints(results []UnittestCaseResult) []EndpointSummary {
+	var out []EndpointSummary
+	index := make(map[string]int)
+	latencies := make(map[string][]int64)
+	for _, r := range results {
+		key := r.Method + " " + r.Endpoint
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, EndpointSummary{Name: r.Endpoint, Method: r.Method})
+		}
+		ep := &out[i]
+		ep.Cases++
+		switch r.Outcome {
+		case OutcomePassed:
+			ep.Passed++
+		case OutcomeFlaky:
+			ep.Flaky++
+		case OutcomeErrored:
+			ep.Errored++
+			continue // never reached the target
+		case OutcomeSkipped:
+			ep.Skipped++
+			continue
+		default:
+			ep.Failed++
+		}
+		latencies[key] = append(latencies[key], r.LatencyMS)
+	}
+
+	for i := range out {
+		lat := latencies[out[i].Method+" "+out[i].Name]
+		if len(lat) == 0 {
+			continue
+		}
+		sort.Slice(lat, func(a, b int) bool { return lat[a] < lat[b] })
+		var sum int64
+		for _, l := range lat {
+			sum += l
+		}
+		out[i].MinMS = lat[0]
+		out[i].MaxMS = lat[len(lat)-1]
+		out[i].AvgMS = sum / int64(len(lat))
+		out[i].P50MS = Percentile(lat, 50)
+		out[i].P95MS = Percentile(lat, 95)
+	}
+	return out
+}
+
+// markdownCell keeps a value on one table row.
+func markdownCell(v any) string {
+	s := fmt.Sprint(v)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func formatEndpointValue(v any) string {
+	formatted, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "```\n" + fmt.Sprint(v) + "\n```\n"
+	}
+	return "```json\n" + truncateMarkdown(string(formatted)) + "\n```\n"
 }
 
 func formatEndpointBody(raw string) string {
@@ -116,17 +429,25 @@ func formatEndpointBody(raw string) string {
 
 	var parsed any
 	if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
-		return "```\n" + trimmed + "\n```\n"
+		return "```\n" + truncateMarkdown(trimmed) + "\n```\n"
 	}
 
 	formatted, err := json.MarshalIndent(parsed, "", "  ")
 	if err != nil {
-		return "```json\n" + trimmed + "\n```\n"
+		return "```json\n" + truncateMarkdown(trimmed) + "\n```\n"
 	}
 
 	var buf bytes.Buffer
 	buf.WriteString("```json\n")
-	buf.Write(formatted)
+	buf.WriteString(truncateMarkdown(string(formatted)))
 	buf.WriteString("\n```\n")
 	return buf.String()
 }
+
+func truncateMarkdown(s string) string {
+	if len(s) <= maxMarkdownBody {
+		return s
+	}
+	cut := truncateUTF8(s, maxMarkdownBody)
+	return cut + fmt.Sprintf("\n... (%d more bytes, see report.json)", len(s)-len(cut))
+}
@@ -0,0 +1,84 @@
+package toolkit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSynraxBaselineCaller(t *testing.T) {
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	type run struct {
+		id       string
+		age      int // days before day
+		filtered bool
+		cases    bool // metric says case rows were uploaded
+		rows     int  // case rows actually stored
+	}
+	tests := []struct {
+		name         string
+		runs         []run
+		wantFound    bool
+		wantRun      string
+		wantFiltered bool
+	}{
+		{name: "no runs"},
+		{
+			name:    "newest with cases",
+			runs:    []run{{id: "old", age: 2, cases: true, rows: 2}, {id: "new", age: 1, cases: true, rows: 3}},
+			wantRun: "new", wantFound: true,
+		},
+		{
+			name:    "newest without cases skipped",
+			runs:    []run{{id: "old", age: 2, cases: true, rows: 2}, {id: "new", age: 1}},
+			wantRun: "old", wantFound: true,
+		},
+		{
+			name:    "filtered run",
+			runs:    []run{{id: "old", age: 2, cases: true, rows: 2}, {id: "new", age: 1, filtered: true, cases: true, rows: 1}},
+			wantRun: "new", wantFound: true, wantFiltered: true,
+		},
+		{
+			name: "case rows missing",
+			runs: []run{{id: "new", age: 1, cases: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newStorageServer(t)
+			rows := map[string]int{}
+			for _, r := range tt.runs {
+				rows[r.id] = r.rows
+				metric := ReportMetric{
+					ID: r.id, RepoID: "repo", TargetBranch: "main",
+					CreatedAt: day.AddDate(0, 0, -r.age), Filtered: r.filtered, CasesStored: r.cases,
+				}
+				if err := SynraxMetricStorage(context.Background(), metric); err != nil {
+					t.Fatal(err)
+				}
+				if r.rows > 0 {
+					if err := SynraxCaseStorage(context.Background(), r.id, testEntry(r.id, r.rows).Cases); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			report, found, err := SynraxBaselineCaller(context.Background(), "repo", "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("found %v, want %v", found, tt.wantFound)
+			}
+			if !found {
+				return
+			}
+			if report.RunID != tt.wantRun || report.Filtered != tt.wantFiltered {
+				t.Errorf("run %q filtered %v, want %q filtered %v", report.RunID, report.Filtered, tt.wantRun, tt.wantFiltered)
+			}
+			if len(report.Results) != rows[tt.wantRun] {
+				t.Errorf("%d results, want %d", len(report.Results), rows[tt.wantRun])
+			}
+		})
+	}
+}
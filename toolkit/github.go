@@ -0,0 +1,251 @@
+package toolkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHub Actions integration: step summary, workflow annotations and a
+// single PR comment that is edited on every run.
+
+// commentMarker identifies the comment this tool owns on a pull request.
+const commentMarker = "<!-- synrax-report -->"
+
+// maxCommentBody is GitHub's limit for an issue comment body.
+const maxCommentBody = 65536
+
+// InGitHubActions reports whether the process runs inside a workflow.
+func InGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// GitHubStepSummary appends the Markdown report to $GITHUB_STEP_SUMMARY.
+// Other steps may have written to the file already, so it is never truncated.
+func GitHubStepSummary(report UnittestReport) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return fmt.Errorf("GITHUB_STEP_SUMMARY is empty")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open step summary: %w", err)
+	}
+	defer f.Close()
+	if err := WriteMarkdown(f, report); err != nil {
+		return fmt.Errorf("write step summary: %w", err)
+	}
+	slog.Info("toolkit.github: step summary written", "path", path)
+	return nil
+}
+
+// GitHubAnnotations writes one ::error workflow command per failed or
+// errored case and per failed hook.
+func GitHubAnnotations(w io.Writer, report UnittestReport) error {
+	for _, r := range report.Results {
+		if r.Outcome != OutcomeFailed && r.Outcome != OutcomeErrored {
+			continue
+		}
+		title := fmt.Sprintf("%s %s %s (%s)", r.Method, r.Endpoint, r.TestID, r.Outcome)
+		msg := stringsJoinNonEmpty(" ", r.Why, r.Error)
+		if _, err := fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty(title), escapeData(msg)); err != nil {
+			return err
+		}
+	}
+	for _, h := range report.Hooks {
+		if h.Passed {
+			continue
+		}
+		title := fmt.Sprintf("%s hook %s (%s)", h.Stage, h.HookID, h.Scope)
+		if _, err := fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty(title), escapeData(h.Error)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property such as title.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func stringsJoinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// GitHubComment says where the PR comment goes.
+type GitHubComment struct {
+	APIURL     string // https://api.github.com, or a stub server in tests
+	Repository string // owner/name
+	PR         int
+	Token      string
+}
+
+// GitHubCommentFromEnv fills the comment target from the workflow environment;
+// fields that are already set win.
+func GitHubCommentFromEnv(c GitHubComment) GitHubComment {
+	if c.APIURL == "" {
+		c.APIURL = stringsOr(os.Getenv("GITHUB_API_URL"), "https://api.github.com")
+	}
+	if c.Repository == "" {
+		c.Repository = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if c.Token == "" {
+		c.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	if c.PR == 0 {
+		c.PR = pullRequestFromEnv()
+	}
+	return c
+}
+
+// pullRequestFromEnv reads the PR number from the event payload, falling
+// back to a refs/pull/<n>/merge ref.
+func pullRequestFromEnv() int {
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		if raw, err := os.ReadFile(path); err == nil {
+			var event struct {
+				Number      int `json:"number"`
+				PullRequest struct {
+					Number int `json:"number"`
+				} `json:"pull_request"`
+			}
+			if json.Unmarshal(raw, &event) == nil {
+				if event.PullRequest.Number > 0 {
+					return event.PullRequest.Number
+				}
+				if event.Number > 0 {
+					return event.Number
+				}
+			}
+		}
+	}
+	ref := os.Getenv("GITHUB_REF")
+	if rest, ok := strings.CutPrefix(ref, "refs/pull/"); ok {
+		if n, err := strconv.Atoi(strings.SplitN(rest, "/", 2)[0]); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// GitHubUpsertComment creates the report comment on the PR, or edits the one
+// a previous run left, so the PR carries a single up to date comment.
+func GitHubUpsertComment(c GitHubComment, report UnittestReport) error {
+	switch {
+	case c.Repository == "":
+		return fmt.Errorf("github comment: repository is empty (set GITHUB_REPOSITORY)")
+	case c.PR <= 0:
+		return fmt.Errorf("github comment: pull request number is unknown (pass --github-pr)")
+	case c.Token == "":
+		return fmt.Errorf("github comment: token is empty (set GITHUB_TOKEN)")
+	}
+
+	md, err := MarkdownWithin(report, maxCommentBody-len(commentMarker)-1, "the workflow step summary")
+	if err != nil {
+		return fmt.Errorf("github comment: %w", err)
+	}
+	body := commentMarker + "\n" + md
+
+	api := strings.TrimRight(c.APIURL, "/")
+	id, err := findComment(c, api)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{"body": body}
+	if id != 0 {
+		slog.Info("toolkit.github: updating comment", "id", id, "pr", c.PR)
+		_, err = githubRequest(c, http.MethodPatch, fmt.Sprintf("%s/repos/%s/issues/comments/%d", api, c.Repository, id), payload)
+	} else {
+		slog.Info("toolkit.github: creating comment", "pr", c.PR)
+		_, err = githubRequest(c, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/comments", api, c.Repository, c.PR), payload)
+	}
+	return err
+}
+
+// findComment returns the id of the comment carrying commentMarker, 0 if none.
+func findComment(c GitHubComment, api string) (int64, error) {
+	for page := 1; page <= 50; page++ {
+		raw, err := githubRequest(c, http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=100&page=%d", api, c.Repository, c.PR, page), nil)
+		if err != nil {
+			return 0, err
+		}
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		if err := json.Unmarshal(raw, &comments); err != nil {
+			return 0, fmt.Errorf("github comment: decode comments: %w", err)
+		}
+		for _, cm := range comments {
+			if strings.HasPrefix(cm.Body, commentMarker) {
+				return cm.ID, nil
+			}
+		}
+		if len(comments) < 100 {
+			return 0, nil
+		}
+	}
+	return 0, nil
+}
+
+func githubRequest(c GitHubComment, method string, url string, payload any) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal payload: %w", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("github %s %s failed with status=%d body=%s", method, url, resp.StatusCode, truncateForLog(raw, 500))
+	}
+	return raw, nil
+}
+
+func stringsOr(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
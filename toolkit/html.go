@@ -0,0 +1,232 @@
+package toolkit
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDiffCells bounds the line diff, len(expected)*len(actual) lines.
+const maxDiffCells = 250000
+
+// latencyBuckets are the upper bounds of the histogram bars in ms; the last
+// bar collects everything slower.
+var latencyBuckets = []int64{10, 25, 50, 100, 250, 500, 1000, 2500}
+
+type HTMLData struct {
+	Generated string
+	Global    GlobalData
+	Endpoints []EndpointSummary
+	Cases     []HTMLCase
+	Histogram []HistogramBar
+}
+
+type HTMLCase struct {
+	Endpoint       string
+	Method         string
+	TestID         string
+	As             string
+	Outcome        string
+	Status         int
+	ExpectedStatus string
+	LatencyMS      int64
+	Failure        string
+	Why            string
+	Error          string
+	Request        *CaseRequest
+	RequestBody    string
+	ExpectedBody   string
+	ActualBody     string
+	Diff           []DiffLine
+	ContentDiff    *ContentDiff
+	Attempts       []CaseAttempt
+}
+
+// DiffLine is one line of the expected vs actual body diff. Op is " ", "-"
+// (only expected) or "+" (only actual).
+type DiffLine struct {
+	Op   string
+	Text string
+}
+
+type HistogramBar struct {
+	Label  string
+	Count  int
+	Height int // percent of the tallest bar
+}
+
+// WriteHTMLReport writes a single self-contained HTML file: no external
+// scripts, styles or fonts.
+func WriteHTMLReport(resultPath string, report UnittestReport) error {
+	return WriteFileAtomic(resultPath, func(file io.Writer) error {
+		return WriteHTML(file, report)
+	})
+}
+
+func WriteHTML(file io.Writer, report UnittestReport) error {
+	html_tmp, err := htmltemplate.New("report.html.tpl").Funcs(htmltemplate.FuncMap{
+		"lower": strings.ToLower,
+	}).ParseFS(templateFS, "templates/report.html.tpl")
+	if err != nil {
+		return err
+	}
+
+	data := HTMLData{
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Global: GlobalData{
+			Total:        report.Summary.Total,
+			Passed:       report.Summary.Passed,
+			Failed:       report.Summary.Failed,
+			Flaky:        report.Summary.Flaky,
+			Errored:      report.Summary.Errored,
+			Skipped:      report.Summary.Skipped,
+			HookFailures: report.Summary.HookFailures,
+			Fixed:        report.Summary.Fixed,
+			Interrupted:  report.Interrupted,
+			RerunOf:      report.RerunOf,
+			Baseline:     report.Baseline,
+		},
+		Endpoints: summarizeEndpoints(report.Results),
+		Histogram: latencyHistogram(report.Results),
+	}
+	for _, r := range report.Results {
+		data.Cases = append(data.Cases, htmlCase(r))
+	}
+	return html_tmp.Execute(file, data)
+}
+
+func htmlCase(r UnittestCaseResult) HTMLCase {
+	c := HTMLCase{
+		Endpoint:       r.Endpoint,
+		Method:         r.Method,
+		TestID:         r.TestID,
+		As:             r.As,
+		Outcome:        r.Outcome,
+		Status:         r.Status,
+		ExpectedStatus: "2xx",
+		LatencyMS:      r.LatencyMS,
+		Failure:        r.Failure,
+		Why:            r.Why,
+		Error:          r.Error,
+		Request:        r.Request,
+		ContentDiff:    r.ContentDiff,
+		Attempts:       r.Attempts,
+	}
+	if len(r.ExpectedStatus) > 0 {
+		c.ExpectedStatus = fmt.Sprint(r.ExpectedStatus)
+	}
+	if r.Request != nil && len(r.Request.Body) > 0 {
+		c.RequestBody = prettyJSON(r.Request.Body)
+	}
+	if r.ExpectedContent != nil {
+		c.ExpectedBody = prettyJSON(r.ExpectedContent)
+	}
+	c.ActualBody = prettyBody(r.Body)
+	if c.ExpectedBody != "" && c.ActualBody != "" && r.Outcome != OutcomePassed {
+		c.Diff = diffLines(strings.Split(c.ExpectedBody, "\n"), strings.Split(c.ActualBody, "\n"))
+	}
+	return c
+}
+
+func prettyJSON(v any) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func prettyBody(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return ""
+	}
+	var parsed any
+	if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
+		return trimmed
+	}
+	return prettyJSON(parsed)
+}
+
+// diffLines is a longest-common-subsequence line diff. Inputs too large to
+// diff cheaply return nil and the report shows both bodies side by side.
+func diffLines(a, b []string) []DiffLine {
+	if len(a)*len(b) > maxDiffCells {
+		return nil
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: " ", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{Op: "+", Text: b[j]})
+	}
+	return out
+}
+
+// latencyHistogram counts cases that reached the target per latency bucket.
+func latencyHistogram(results []UnittestCaseResult) []HistogramBar {
+	counts := make([]int, len(latencyBuckets)+1)
+	measured := 0
+	for _, r := range results {
+		if r.Outcome == OutcomeSkipped || r.Outcome == OutcomeErrored {
+			continue
+		}
+		measured++
+		i := sort.Search(len(latencyBuckets), func(i int) bool { return r.LatencyMS < latencyBuckets[i] })
+		counts[i]++
+	}
+	if measured == 0 {
+		return nil
+	}
+
+	tallest := 0
+	for _, c := range counts {
+		tallest = max(tallest, c)
+	}
+	bars := make([]HistogramBar, len(counts))
+	var lower int64
+	for i, c := range counts {
+		label := fmt.Sprintf("≥%d", lower)
+		if i < len(latencyBuckets) {
+			label = fmt.Sprintf("%d–%d", lower, latencyBuckets[i])
+			lower = latencyBuckets[i]
+		}
+		bars[i] = HistogramBar{Label: label, Count: c, Height: c * 100 / tallest}
+	}
+	return bars
+}
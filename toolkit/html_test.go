@@ -0,0 +1,96 @@
+package toolkit
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []DiffLine
+	}{
+		{"equal", []string{"{", "}"}, []string{"{", "}"}, []DiffLine{{" ", "{"}, {" ", "}"}}},
+		{
+			"changed line",
+			[]string{"{", `  "id": 1,`, `  "name": "a"`, "}"},
+			[]string{"{", `  "id": 2,`, `  "name": "a"`, "}"},
+			[]DiffLine{{" ", "{"}, {"-", `  "id": 1,`}, {"+", `  "id": 2,`}, {" ", `  "name": "a"`}, {" ", "}"}},
+		},
+		{"inserted", []string{"a", "c"}, []string{"a", "b", "c"}, []DiffLine{{" ", "a"}, {"+", "b"}, {" ", "c"}}},
+		{"removed tail", []string{"a", "b", "c"}, []string{"a"}, []DiffLine{{" ", "a"}, {"-", "b"}, {"-", "c"}}},
+		{"nothing expected", nil, []string{"x"}, []DiffLine{{"+", "x"}}},
+		{"both empty", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+
+	big := make([]string, 600)
+	if got := diffLines(big, big); got != nil {
+		t.Errorf("%d lines for inputs over maxDiffCells, want none", len(got))
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	if got := latencyHistogram([]UnittestCaseResult{{Outcome: OutcomeSkipped}, {Outcome: OutcomeErrored}}); got != nil {
+		t.Errorf("histogram without measured cases: %v", got)
+	}
+
+	var results []UnittestCaseResult
+	for _, ms := range []int64{0, 9, 10, 24, 24, 99, 2500, 40000} {
+		results = append(results, UnittestCaseResult{Outcome: OutcomePassed, LatencyMS: ms})
+	}
+	results = append(results,
+		UnittestCaseResult{Outcome: OutcomeErrored, LatencyMS: 5},
+		UnittestCaseResult{Outcome: OutcomeSkipped, LatencyMS: 5},
+		UnittestCaseResult{Outcome: OutcomeFailed, LatencyMS: 300},
+	)
+	want := []HistogramBar{
+		{"0–10", 2, 66}, {"10–25", 3, 100}, {"25–50", 0, 0}, {"50–100", 1, 33}, {"100–250", 0, 0},
+		{"250–500", 1, 33}, {"500–1000", 0, 0}, {"1000–2500", 0, 0}, {"≥2500", 2, 66},
+	}
+	if got := latencyHistogram(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	report := UnittestReport{
+		Summary: UnittestSummary{Total: 2, Passed: 1, Failed: 1},
+		Results: []UnittestCaseResult{
+			{Endpoint: "/items", Method: "GET", TestID: "list", Outcome: OutcomePassed, Status: 200, LatencyMS: 12},
+			{
+				Endpoint: "/items", Method: "POST", TestID: "create", Outcome: OutcomeFailed, Status: 201,
+				ExpectedContent: map[string]any{"name": "<b>a</b>"}, Body: `{"name":"<script>x</script>"}`,
+			},
+		},
+	}
+	var out bytes.Buffer
+	if err := WriteHTML(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{
+		`<span class="del">−   &#34;name&#34;: &#34;\u003cb\u003ea\u003c/b\u003e&#34;</span>`,
+		`<span class="add">+   &#34;name&#34;: &#34;\u003cscript\u003ex\u003c/script\u003e&#34;</span>`,
+		`title="10–25 ms: 1 cases"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report lacks %s", want)
+		}
+	}
+	if strings.Contains(html, "<script>x") {
+		t.Error("response body is not escaped")
+	}
+	// self-contained: nothing is loaded from elsewhere
+	if external := regexp.MustCompile(`(?i)(src|href)=["']?(https?:)?//`).FindString(html); external != "" {
+		t.Errorf("external resource %s", external)
+	}
+}
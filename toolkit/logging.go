@@ -0,0 +1,78 @@
+package toolkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats for SetupLogging.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+type LogOptions struct {
+	Level  string // debug, info, warn or error
+	Format string // text or json
+	Quiet  bool   // errors only, wins over Level
+}
+
+// SetupLogging installs the default slog logger for cli, reporter and toolkit.
+// attrs are added to every record, e.g. the run id. Records logged through
+// the standard log package end up in the same handler at info level.
+func SetupLogging(w io.Writer, o LogOptions, attrs ...any) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(stringsOr(o.Level, "info"))); err != nil {
+		return fmt.Errorf("invalid log level %q (want debug, info, warn or error)", o.Level)
+	}
+	if o.Quiet {
+		level = slog.LevelError
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(stringsOr(o.Format, LogFormatText)) {
+	case LogFormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return fmt.Errorf("invalid log format %q (want %s or %s)", o.Format, LogFormatText, LogFormatJSON)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}).With(attrs...))
+	return nil
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns a context whose records carry attrs, on top of the
+// ones ctx already carries. Log with slog.*Context to pick them up.
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(append(merged, prev...), attrs...)
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// contextHandler adds the attributes of WithLogAttrs to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
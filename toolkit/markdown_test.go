@@ -0,0 +1,74 @@
+package toolkit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func failingReport(cases int, body string) UnittestReport {
+	var rep UnittestReport
+	for i := range cases {
+		rep.Results = append(rep.Results, UnittestCaseResult{
+			Endpoint: "/items", Method: "GET", TestID: fmt.Sprintf("case-%03d", i),
+			Outcome: OutcomeFailed, Failure: "status_mismatch", Status: 500, Body: body,
+		})
+	}
+	rep.Summary.Total = cases
+	rep.Summary.Failed = cases
+	return rep
+}
+
+func TestMarkdownWithin(t *testing.T) {
+	tests := []struct {
+		name      string
+		report    UnittestReport
+		limit     int
+		truncated bool
+	}{
+		{"fits", failingReport(3, `{"error":"boom"}`), 65536, false},
+		{"many failures", failingReport(200, `{"error":"`+strings.Repeat("é", 600)+`"}`), 65536, true},
+		{"tiny limit", failingReport(5, `{"error":"boom"}`), 1200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarkdownWithin(tt.report, tt.limit, "the step summary")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) > tt.limit {
+				t.Errorf("length %d over limit %d", len(got), tt.limit)
+			}
+			if !utf8.ValidString(got) {
+				t.Error("not valid UTF-8")
+			}
+			if open, closed := strings.Count(got, "<details>"), strings.Count(got, "</details>"); open != closed {
+				t.Errorf("%d <details> but %d </details>", open, closed)
+			}
+			if fences := strings.Count(got, "```"); fences%2 != 0 {
+				t.Errorf("odd number of code fences: %d", fences)
+			}
+			if has := strings.Contains(got, "_Report truncated"); has != tt.truncated {
+				t.Errorf("truncation note %v, want %v", has, tt.truncated)
+			}
+			if !tt.truncated {
+				var full bytes.Buffer
+				if err := WriteMarkdown(&full, tt.report); err != nil {
+					t.Fatal(err)
+				}
+				if got != full.String() {
+					t.Error("report that fits differs from WriteMarkdown")
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateMarkdownAtRune(t *testing.T) {
+	got := truncateMarkdown(strings.Repeat("é", maxMarkdownBody))
+	if !utf8.ValidString(got) {
+		t.Error("not valid UTF-8")
+	}
+}
@@ -5,6 +5,103 @@ import "time"
 type UnittestConfig struct {
 	AuthToken string `json:"auth_token"`
 	BaseURL   string `json:"base"` // "http://localhost:8000" example
+
+	Auth            *AuthConfig         `json:"auth,omitempty"`             // suite default scheme, takes precedence over AuthToken
+	Identities      map[string]Identity `json:"identities,omitempty"`       // "user", "admin" example
+	DefaultIdentity string              `json:"default_identity,omitempty"` // used when a test has no `as`; Auth/AuthToken otherwise
+	AuthzMatrix     bool                `json:"authz_matrix,omitempty"`     // run every endpoint under every identity
+
+	CookieJar string      `json:"cookie_jar,omitempty"` // "suite" shares one jar, "identity" keeps one per identity; off when empty
+	CSRF      *CSRFConfig `json:"csrf,omitempty"`
+
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Network routing for the target client; spec URLs are left untouched.
+	Proxy         string            `json:"proxy,omitempty"`          // "http://127.0.0.1:8080" example, HTTP(S)_PROXY env when empty
+	HostOverrides map[string]string `json:"host_overrides,omitempty"` // "api.internal" or "api.internal:443" -> "10.0.3.17:8443"
+	UnixSocket    string            `json:"unix_socket,omitempty"`    // dial every request to this socket path
+
+	Timeouts *Timeouts    `json:"timeouts,omitempty"`
+	Retry    *RetryPolicy `json:"retry,omitempty"` // default for every test
+}
+
+// RetryPolicy re-executes a failing case. The most specific policy wins:
+// Test, then Endpoint, then UnittestConfig. With neither OnStatus nor
+// OnFailure set, transport errors, timeouts and 502/503/504 are retried.
+type RetryPolicy struct {
+	Count     int      `json:"count"`    // extra attempts after the first
+	DelayMS   int      `json:"delay_ms"` // wait between attempts
+	OnStatus  []int    `json:"on_status,omitempty"`
+	OnFailure []string `json:"on_failure,omitempty"` // failure types, "transport_error" example
+}
+
+// Timeouts are per-request budgets in milliseconds. Test values override
+// Endpoint values, which override UnittestConfig; zero means inherit.
+// Defaults are 10s connect, 10s TLS and 15s total; connect and TLS default
+// to at most the total budget.
+type Timeouts struct {
+	ConnectMS int `json:"connect_ms,omitempty"` // DNS + TCP connect
+	TLSMS     int `json:"tls_ms,omitempty"`     // TLS handshake
+	TotalMS   int `json:"total_ms,omitempty"`   // whole request including the response body
+}
+
+// TLSConfig configures the client used against the target service.
+type TLSConfig struct {
+	CAFile             string `json:"ca_file,omitempty"`   // PEM bundle added to the system roots
+	CertFile           string `json:"cert_file,omitempty"` // client certificate for mTLS
+	KeyFile            string `json:"key_file,omitempty"`
+	MinVersion         string `json:"min_version,omitempty"` // "1.2" or "1.3"
+	ServerName         string `json:"server_name,omitempty"` // SNI / verification name override
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// CSRFConfig copies a token from a jar cookie into a request header on
+// unsafe methods (POST, PUT, PATCH, DELETE). Requires CookieJar.
+type CSRFConfig struct {
+	Cookie string `json:"cookie"` // "csrftoken" example
+	Header string `json:"header"` // "X-CSRF-Token" example
+}
+
+// Identity is a named credential a test can select with `as`. Token is a
+// shorthand for a static bearer Auth.
+type Identity struct {
+	Token string      `json:"token,omitempty"`
+	Auth  *AuthConfig `json:"auth,omitempty"`
+}
+
+// AuthConfig describes how credentials are attached to target requests.
+// Only the fields of the selected Type are read.
+type AuthConfig struct {
+	Type string `json:"type"` // bearer, basic, api_key, oauth2_client_credentials, jwt
+
+	// bearer
+	Token string `json:"token,omitempty"`
+
+	// basic
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// api_key
+	APIKey string `json:"api_key,omitempty"`
+	In     string `json:"in,omitempty"`   // header (default) or query
+	Name   string `json:"name,omitempty"` // header or query parameter name, "X-API-Key" default
+
+	// oauth2_client_credentials
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	ClientAuth   string   `json:"client_auth,omitempty"` // basic (default) or body
+
+	// jwt, minted locally and re-minted before it expires
+	KeyFile    string         `json:"key_file,omitempty"`  // PEM private key, or raw secret for HS*
+	Algorithm  string         `json:"algorithm,omitempty"` // "RS256" default
+	KeyID      string         `json:"key_id,omitempty"`
+	Issuer     string         `json:"issuer,omitempty"`
+	Subject    string         `json:"subject,omitempty"`
+	Audience   []string       `json:"audience,omitempty"`
+	TTLSeconds int            `json:"ttl_seconds,omitempty"` // 300 default
+	Claims     map[string]any `json:"claims,omitempty"`
 }
 
 // -- Test Spec
@@ -12,20 +109,66 @@ type UnittestConfig struct {
 type TestSpec struct {
 	BaseURL   string     `json:"base_url"`
 	Endpoints []Endpoint `json:"endpoints"`
+
+	Hooks
 }
 
 type Endpoint struct {
-	Name   string `json:"name"`
-	Method string `json:"method"`
-	Tests  []Test `json:"tests"`
+	Name     string       `json:"name"`
+	Method   string       `json:"method"`
+	Tests    []Test       `json:"tests"`
+	Tags     []string     `json:"tags,omitempty"` // inherited by every test, "smoke" or "destructive" example
+	Timeouts *Timeouts    `json:"timeouts,omitempty"`
+	Retry    *RetryPolicy `json:"retry,omitempty"`
+
+	// Authz maps identity name to the expected outcome in authz matrix mode:
+	// "allow" (2xx), "forbidden" (403) or "unauthorized" (401).
+	Authz map[string]string `json:"authz,omitempty"`
+
+	Hooks
 }
 
-type Test struct {
+// Hooks are setup/teardown requests that run around tests. On TestSpec they
+// wrap the whole suite, on Endpoint only that endpoint's tests. When a
+// before_* hook fails the cases it wraps are errored without being sent;
+// the matching after_* hooks still run.
+type Hooks struct {
+	BeforeAll  []Hook `json:"before_all,omitempty"`
+	AfterAll   []Hook `json:"after_all,omitempty"`
+	BeforeEach []Hook `json:"before_each,omitempty"`
+	AfterEach  []Hook `json:"after_each,omitempty"`
+}
+
+type Hook struct {
 	ID          string       `json:"id"`
+	Name        string       `json:"name"` // endpoint path, "/v1/items/{item_id}" example
+	Method      string       `json:"method"`
+	As          string       `json:"as,omitempty"`
 	Request     RequestSpecs `json:"request"`
 	Expectation Expectation  `json:"expect"`
 }
 
+type Test struct {
+	ID          string         `json:"id"`
+	As          string         `json:"as,omitempty"` // identity name, or "anonymous" to send no credentials
+	Tags        []string       `json:"tags,omitempty"`
+	Request     RequestSpecs   `json:"request"`
+	Expectation Expectation    `json:"expect"`
+	RateLimit   *RateLimitSpec `json:"rate_limit,omitempty"`
+	Timeouts    *Timeouts      `json:"timeouts,omitempty"`
+	Retry       *RetryPolicy   `json:"retry,omitempty"`
+}
+
+// RateLimitSpec turns a test into a rate-limit assertion: the runner sends
+// just enough requests to cross Limit within the window and expects a 429.
+// A 429 before the request that crosses Limit fails as rate_limit_early.
+type RateLimitSpec struct {
+	Limit          int  `json:"limit"`
+	WindowSeconds  int  `json:"window_seconds"`   // 60 when unset
+	VerifyRecovery bool `json:"verify_recovery"`  // wait for the window to reset and expect a non-429
+	MaxWaitSeconds int  `json:"max_wait_seconds"` // cap on the recovery wait, window + 5s when unset
+}
+
 type RequestSpecs struct {
 	PathParams map[string]string `json:"path_params"`
 	Query      map[string]string `json:"query"`
@@ -34,41 +177,220 @@ type RequestSpecs struct {
 }
 
 type Expectation struct {
-	Status  []int `json:"status"`
-	Content any   `json:"content"`
+	Status  []int                        `json:"status"`
+	Content any                          `json:"content"`
+	Cookies map[string]CookieExpectation `json:"cookies,omitempty"` // keyed by cookie name, each must be set by the response
+}
+
+// CookieExpectation asserts Set-Cookie attributes. Unset fields are not checked.
+type CookieExpectation struct {
+	Value    string `json:"value,omitempty"` // "..." accepts any non-empty value
+	HttpOnly *bool  `json:"http_only,omitempty"`
+	Secure   *bool  `json:"secure,omitempty"`
+	SameSite string `json:"same_site,omitempty"` // Strict, Lax or None
 }
 
 // -- Report
 
 type UnittestReport struct { // !!!! \\\
 	// Final Unittest Report Structure. This is the main exporting struct.
-	Summary   UnittestSummary      `json:"summary"`
-	Persisted bool                 `json:"persisted"`
-	Results   []UnittestCaseResult `json:"results"`
+	RunID       string               `json:"run_id,omitempty"` // links stored metrics and case results of one run
+	Summary     UnittestSummary      `json:"summary"`
+	Persisted   bool                 `json:"persisted"`
+	Interrupted bool                 `json:"interrupted"` // cancelled by a signal, results are partial
+	Results     []UnittestCaseResult `json:"results"`
+	Hooks       []UnittestHookResult `json:"hooks,omitempty"`
+	RerunOf     string               `json:"rerun_of,omitempty"` // previous report.json the failed cases were rerun from
+	Spec        *TestSpec            `json:"spec,omitempty"`     // spec the run executed, reused by rerun
+	Baseline    *BaselineComparison  `json:"baseline,omitempty"`
+	Filtered    bool                 `json:"filtered,omitempty"` // a filter selected the cases, the rest of the suite did not run
+
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`         // wall time of the run, hooks included
+	Commit     string    `json:"commit,omitempty"`    // commit SHA the run tested
+	SpecHash   string    `json:"spec_hash,omitempty"` // sha256 of the spec, see SpecHash
 }
 
 type UnittestSummary struct {
 	Total  int `json:"total"`
 	Passed int `json:"passed"`
 	Failed int `json:"failed"`
+
+	Flaky        int `json:"flaky"`           // passed only after a retry, not counted in Passed
+	Errored      int `json:"errored"`         // the test itself is broken (bad spec or config), not the API
+	Skipped      int `json:"skipped"`         // not executed
+	HookFailures int `json:"hook_failures"`   // not counted in Failed
+	Fixed        int `json:"fixed,omitempty"` // rerun cases that did not pass before and pass now
 }
 
+// Case outcomes. Passed on UnittestCaseResult is true for OutcomePassed and OutcomeFlaky.
+const (
+	OutcomePassed  = "passed"
+	OutcomeFlaky   = "flaky"
+	OutcomeFailed  = "failed"
+	OutcomeErrored = "errored"
+	OutcomeSkipped = "skipped"
+)
+
 type UnittestCaseResult struct {
 	Endpoint string `json:"endpoint"`
 	Method   string `json:"method"`
 	TestID   string `json:"test_id"`
+	As       string `json:"as,omitempty"`
+	Outcome  string `json:"outcome"` // one of the Outcome* constants
 	Passed   bool   `json:"passed"`
-	Failure  string `json:"failure_type,omitempty"`
-	Why      string `json:"why_failed,omitempty"`
-	Error    string `json:"error,omitempty"`
+
+	Rerun           bool   `json:"rerun,omitempty"`            // executed again by `rerun`
+	PreviousOutcome string `json:"previous_outcome,omitempty"` // outcome in the report the rerun started from
+
+	Failure string `json:"failure_type,omitempty"`
+	Why     string `json:"why_failed,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	TimeoutPhase string `json:"timeout_phase,omitempty"` // connect, tls or total when Failure is "timeout"
+
+	Request *CaseRequest `json:"request,omitempty"` // what was sent, credentials redacted
 
 	ExpectedStatus  []int `json:"expected_status,omitempty"`
 	ExpectedContent any   `json:"expected_content,omitempty"`
 
-	Status int    `json:"status"`
-	Body   string `json:"body,omitempty"`
+	Status      int          `json:"status"`
+	Body        string       `json:"body,omitempty"`
+	ContentDiff *ContentDiff `json:"content_diff,omitempty"` // set on content_mismatch
 
 	LatencyMS int64 `json:"latency_ms"`
+
+	RateLimit *RateLimitResult `json:"rate_limit,omitempty"`
+	Attempts  []CaseAttempt    `json:"attempts,omitempty"` // every attempt when the case was retried, last one included
+}
+
+type CaseRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // headers from the spec, injected auth excluded
+	Body    map[string]any    `json:"body,omitempty"`
+}
+
+// ContentDiff is the first place where the response content differs from
+// the expectation. Expected and Actual are compact JSON.
+type ContentDiff struct {
+	Path     string `json:"path"` // "$.data[0].id" example
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type CaseAttempt struct {
+	Status    int    `json:"status"`
+	Failure   string `json:"failure_type,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// Baseline changes. Cases that pass in both runs are not listed.
+const (
+	ChangeNewFailure   = "new_failure"   // passed in the baseline, fails now
+	ChangeFixed        = "fixed"         // failed in the baseline, passes now
+	ChangeStillFailing = "still_failing" // fails in both runs
+	ChangeNewTest      = "new_test"      // not in the baseline
+	ChangeRemovedTest  = "removed_test"  // in the baseline, not in this run
+	ChangeUnknown      = "unknown"       // not in a filtered baseline, its earlier outcome is unknown
+)
+
+// BaselineComparison classifies the cases of a run against a previous run.
+// Failed and errored cases count as failing; skipped cases are not compared.
+type BaselineComparison struct {
+	Source string `json:"source"` // report file or "storage"
+
+	NewFailures     int `json:"new_failures"`
+	Fixed           int `json:"fixed"`
+	StillFailing    int `json:"still_failing"`
+	NewTests        int `json:"new_tests"`
+	NewTestFailures int `json:"new_test_failures"` // new tests that fail, they block like new failures
+	RemovedTests    int `json:"removed_tests"`
+	Unknown         int `json:"unknown"` // cases a filtered baseline did not run, never regressions
+
+	Cases []BaselineCase `json:"cases,omitempty"`
+}
+
+// Regressions counts failures the baseline does not already know about.
+func (b *BaselineComparison) Regressions() int {
+	return b.NewFailures + b.NewTestFailures
+}
+
+type BaselineCase struct {
+	Endpoint        string `json:"endpoint"`
+	Method          string `json:"method"`
+	TestID          string `json:"test_id"`
+	Change          string `json:"change"`
+	Outcome         string `json:"outcome,omitempty"` // empty for removed tests
+	BaselineOutcome string `json:"baseline_outcome,omitempty"`
+}
+
+type RateLimitResult struct {
+	Limit             int    `json:"limit"`
+	AlreadyUsed       int    `json:"already_used"`        // requests sent with the same token earlier in the window
+	Sent              int    `json:"sent"`                // requests sent by this test, recovery probe excluded
+	ExpectedLimitedAt int    `json:"expected_limited_at"` // 1-based index that should cross the limit
+	FirstLimitedAt    int    `json:"first_limited_at"`    // 1-based index of the first 429, 0 if never limited
+	RetryAfter        string `json:"retry_after,omitempty"`
+	Recovered         *bool  `json:"recovered,omitempty"`
+}
+
+type UnittestHookResult struct {
+	Stage    string `json:"stage"` // before_all, after_all, before_each, after_each
+	Scope    string `json:"scope"` // "suite" or the owning endpoint name
+	HookID   string `json:"hook_id"`
+	Endpoint string `json:"endpoint"`
+	Method   string `json:"method"`
+	TestID   string `json:"test_id,omitempty"` // case wrapped by a *_each hook
+	Passed   bool   `json:"passed"`
+	Failure  string `json:"failure_type,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	Status    int   `json:"status"`
+	LatencyMS int64 `json:"latency_ms"`
+}
+
+// CaseRecord is one row of the unittest_case_results table: a case result
+// with bodies redacted and capped, linked to its run by RunID.
+type CaseRecord struct {
+	RunID        string    `json:"run_id"`
+	RepoID       string    `json:"repo_id"`
+	TargetBranch string    `json:"target_branch"`
+	Commit       string    `json:"commit,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	UnittestCaseResult
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+}
+
+// -- Events
+
+// Event types of the NDJSON progress stream, in the order a run emits them.
+const (
+	EventRunStart   = "run_start"
+	EventCaseStart  = "case_start"
+	EventCaseFinish = "case_finish"
+	EventRunSummary = "run_summary"
+)
+
+// RunEvent is one line of the NDJSON progress stream.
+type RunEvent struct {
+	Event string    `json:"event"` // one of the Event* constants
+	Seq   int       `json:"seq"`   // 1-based, increases by one per event
+	Time  time.Time `json:"time"`
+
+	Cases int `json:"cases,omitempty"` // run_start: cases the run will report
+
+	Endpoint string `json:"endpoint,omitempty"` // case_start
+	Method   string `json:"method,omitempty"`
+	TestID   string `json:"test_id,omitempty"`
+	As       string `json:"as,omitempty"`
+
+	Result *UnittestCaseResult `json:"result,omitempty"` // case_finish
+
+	Summary     *UnittestSummary `json:"summary,omitempty"` // run_summary
+	Interrupted bool             `json:"interrupted,omitempty"`
 }
 
 // Report Metric Submission
@@ -79,17 +401,67 @@ type ReportMetric struct {
 	TotalTests  int     `json:"total_tests"`
 	Passed      int     `json:"passed"`
 	Failed      int     `json:"failed"`
-	SuccessRate float32 `json:"success_rate"`
+	Flaky       int     `json:"flaky"`
+	Errored     int     `json:"errored"`
+	Skipped     int     `json:"skipped"`
+	SuccessRate float32 `json:"success_rate"` // passed and flaky out of executed (non-skipped) cases
+
+	DurationMS int64  `json:"duration_ms"`
+	Commit     string `json:"commit,omitempty"`
+	SpecHash   string `json:"spec_hash,omitempty"`
 
 	GetCounts    int `json:"get_counts"`
 	PostCounts   int `json:"post_counts"`
 	PutCounts    int `json:"put_counts"`
 	DeleteCounts int `json:"delete_counts"`
 
-	UniqueEndpointsCount int     	 `json:"unique_endpoint_counts"`
-	CreatedAt            time.Time	 `json:"created_at"`
-	AverageLatency       float32 	 `json:"average_latency"`
-	TargetBranch		 string  	 `json:"target_branch"`
+	UniqueEndpointsCount int       `json:"unique_endpoint_counts"`
+	CreatedAt            time.Time `json:"created_at"`
+	AverageLatency       float32   `json:"average_latency"` // cases that reached the target, like the percentiles
+	TargetBranch         string    `json:"target_branch"`
+
+	LatencyP50MS int64 `json:"latency_p50_ms"`
+	LatencyP90MS int64 `json:"latency_p90_ms"`
+	LatencyP95MS int64 `json:"latency_p95_ms"`
+	LatencyP99MS int64 `json:"latency_p99_ms"`
+	LatencyMaxMS int64 `json:"latency_max_ms"`
+
+	Endpoints    []MetricBreakdown `json:"endpoints,omitempty"`     // per endpoint and method, in report order
+	Methods      []MetricBreakdown `json:"methods,omitempty"`       // per method, sorted
+	FailureTypes map[string]int    `json:"failure_types,omitempty"` // failed and errored cases per failure_type
+
+	Filtered    bool `json:"filtered"`     // a filter selected the cases, see UnittestReport.Filtered
+	CasesStored bool `json:"cases_stored"` // case rows were uploaded, the run can be a baseline
+}
+
+// MetricBreakdown counts outcomes for one endpoint or one method. Endpoint is
+// empty in the per-method breakdown.
+type MetricBreakdown struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Method   string `json:"method"`
+	Cases    int    `json:"cases"`
+	Passed   int    `json:"passed"`
+	Failed   int    `json:"failed"`
+	Flaky    int    `json:"flaky"`
+	Errored  int    `json:"errored"`
+	Skipped  int    `json:"skipped"`
+
+	LatencyP50MS int64 `json:"latency_p50_ms"`
+	LatencyP95MS int64 `json:"latency_p95_ms"`
+	LatencyMaxMS int64 `json:"latency_max_ms"`
+}
+
+// OutboxEntry is a submission the storage API did not accept, kept on disk
+// until `sync` stores it. ID is the idempotency key: the metric id, which is
+// also the run id of the case rows.
+type OutboxEntry struct {
+	ID          string       `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Attempts    int          `json:"attempts"`
+	LastAttempt time.Time    `json:"last_attempt"`
+	LastError   string       `json:"last_error,omitempty"`
+	Metric      ReportMetric `json:"metric"`
+	Cases       []CaseRecord `json:"cases,omitempty"`
 }
 
 // -- API Responses
@@ -0,0 +1,127 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The outbox keeps submissions that failed after the tests ran, one file per
+// run named after its idempotency key, so a storage outage does not lose the
+// metrics. SyncOutbox retries them; a file is removed once stored.
+
+const outboxExt = ".json"
+
+// WriteOutbox saves entry in dir and returns the file path. Writing the same
+// id again replaces the file.
+func WriteOutbox(dir string, entry OutboxEntry) (string, error) {
+	if entry.ID == "" || entry.ID != filepath.Base(entry.ID) || strings.HasPrefix(entry.ID, ".") {
+		return "", fmt.Errorf("outbox: invalid id %q", entry.ID)
+	}
+	path := filepath.Join(dir, entry.ID+outboxExt)
+	err := WriteFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entry)
+	})
+	if err != nil {
+		return "", fmt.Errorf("outbox: %w", err)
+	}
+	return path, nil
+}
+
+// PendingOutbox lists the entry files in dir, oldest name first. A missing
+// dir has no pending entries.
+func PendingOutbox(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("outbox: %w", err)
+	}
+	var paths []string
+	for _, f := range files {
+		name := f.Name()
+		// skips the temp files of an interrupted WriteFileAtomic
+		if f.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != outboxExt {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func ReadOutbox(path string) (OutboxEntry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return OutboxEntry{}, fmt.Errorf("outbox: %w", err)
+	}
+	var entry OutboxEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return OutboxEntry{}, fmt.Errorf("outbox: decode %s: %w", path, err)
+	}
+	if entry.ID == "" || entry.ID != entry.Metric.ID {
+		return OutboxEntry{}, fmt.Errorf("outbox: %s: id %q does not match metric id %q", path, entry.ID, entry.Metric.ID)
+	}
+	return entry, nil
+}
+
+// StoreOutboxEntry submits entry again. The creates ignore rows the API
+// already holds (see SynraxMetricStorage), so an entry whose first attempt
+// was partly stored does not duplicate rows.
+func StoreOutboxEntry(ctx context.Context, entry OutboxEntry) error {
+	return SynraxRunStorage(ctx, entry.Metric, entry.Cases)
+}
+
+// SyncOutbox retries every entry in dir. Stored entries are removed; failed
+// ones stay with their attempt count and last error updated. It returns how
+// many entries were stored and how many are still pending. A cancelled ctx
+// leaves the remaining entries untouched and is returned as the error.
+func SyncOutbox(ctx context.Context, dir string) (synced int, pending int, err error) {
+	paths, err := PendingOutbox(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, path := range paths {
+		if ctx.Err() != nil {
+			pending += len(paths) - i
+			break
+		}
+		entry, err := ReadOutbox(path)
+		if err != nil {
+			// left in place for a human to look at
+			slog.Error("toolkit.outbox: unreadable entry", "path", path, "error", err)
+			pending++
+			continue
+		}
+		entry.Attempts++
+		entry.LastAttempt = time.Now().UTC()
+		if err := StoreOutboxEntry(ctx, entry); err != nil {
+			slog.Warn("toolkit.outbox: retry failed", "id", entry.ID, "attempts", entry.Attempts, "error", err)
+			entry.LastError = err.Error()
+			if _, werr := WriteOutbox(dir, entry); werr != nil {
+				slog.Error("toolkit.outbox: update failed", "id", entry.ID, "error", werr)
+			}
+			pending++
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			// stored; a later sync stores nothing new and removes the file
+			slog.Error("toolkit.outbox: remove failed", "path", path, "error", err)
+			pending++
+			continue
+		}
+		slog.Info("toolkit.outbox: stored", "id", entry.ID, "attempts", entry.Attempts)
+		synced++
+	}
+	return synced, pending, ctx.Err()
+}
@@ -0,0 +1,283 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// storageServer is a fake storage API. Creates honour on_conflict like a
+// unique key with "do nothing", and a repeated Idempotency-Key gets a 409.
+type storageServer struct {
+	mu      sync.Mutex
+	rows    map[string]map[string]json.RawMessage // table -> unique key -> row
+	keys    map[string]bool
+	creates int
+
+	failCreate func(n int, table string) bool // 500 for the n-th create
+	conflict   bool                           // every case create answers 409
+}
+
+func newStorageServer(t *testing.T) *storageServer {
+	s := &storageServer{rows: map[string]map[string]json.RawMessage{}, keys: map[string]bool{}}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	t.Setenv("SYNRAX_API_BASE_URL", srv.URL)
+	return s
+}
+
+func (s *storageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	table := r.URL.Query().Get("table")
+	raw, _ := io.ReadAll(r.Body)
+
+	switch r.URL.Path {
+	case "/db/read":
+		var req struct {
+			Filter map[string]any
+			Order  string
+			Limit  int
+		}
+		json.Unmarshal(raw, &req)
+		out := []json.RawMessage{}
+		var created []string
+		for _, row := range s.rows[table] {
+			var fields map[string]any
+			json.Unmarshal(row, &fields)
+			match := true
+			for k, v := range req.Filter {
+				match = match && fmt.Sprint(fields[k]) == fmt.Sprint(v)
+			}
+			if match {
+				out = append(out, row)
+				created = append(created, fmt.Sprint(fields["created_at"]))
+			}
+		}
+		if req.Order == "-created_at" {
+			sort.Sort(sort.Reverse(byKey{out, created}))
+		}
+		if req.Limit > 0 && len(out) > req.Limit {
+			out = out[:req.Limit]
+		}
+		json.NewEncoder(w).Encode(map[string]any{"status": "success", "response": out})
+	case "/db/create":
+		s.creates++
+		if s.failCreate != nil && s.failCreate(s.creates, table) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			if s.keys[key] || (s.conflict && table == "unittest_case_results") {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			s.keys[key] = true
+		}
+		var one struct{ Schema json.RawMessage }
+		json.Unmarshal(raw, &one)
+		var rows []json.RawMessage
+		if json.Unmarshal(one.Schema, &rows) != nil {
+			rows = []json.RawMessage{one.Schema}
+		}
+		cols := strings.Split(r.URL.Query().Get("on_conflict"), ",")
+		if s.rows[table] == nil {
+			s.rows[table] = map[string]json.RawMessage{}
+		}
+		for _, row := range rows {
+			var fields map[string]any
+			json.Unmarshal(row, &fields)
+			var key []string
+			for _, c := range cols {
+				key = append(key, fmt.Sprint(fields[c]))
+			}
+			if _, ok := s.rows[table][strings.Join(key, "|")]; !ok {
+				s.rows[table][strings.Join(key, "|")] = row
+			}
+		}
+	}
+}
+
+// byKey sorts rows by a parallel slice of keys.
+type byKey struct {
+	rows []json.RawMessage
+	keys []string
+}
+
+func (b byKey) Len() int           { return len(b.rows) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.rows[i], b.rows[j] = b.rows[j], b.rows[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func (s *storageServer) count(table string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rows[table])
+}
+
+func testEntry(id string, cases int) OutboxEntry {
+	entry := OutboxEntry{ID: id, Metric: ReportMetric{ID: id, RepoID: "r"}}
+	for i := range cases {
+		entry.Cases = append(entry.Cases, CaseRecord{
+			RunID:              id,
+			UnittestCaseResult: UnittestCaseResult{Endpoint: "/e", Method: "GET", TestID: fmt.Sprintf("t%03d", i)},
+		})
+	}
+	return entry
+}
+
+func TestCaseBatchKey(t *testing.T) {
+	rows := testEntry("run", 3).Cases
+	reversed := []CaseRecord{rows[2], rows[1], rows[0]}
+	if caseBatchKey("run", rows) != caseBatchKey("run", reversed) {
+		t.Error("key depends on row order")
+	}
+	tests := []struct {
+		name string
+		a, b []CaseRecord
+	}{
+		{"different rows", rows[:2], rows[1:]},
+		{"subset", rows, rows[:2]},
+		{"identity", rows[:1], []CaseRecord{{UnittestCaseResult: UnittestCaseResult{Endpoint: "/e", Method: "GET", TestID: "t000", As: "admin"}}}},
+	}
+	for _, tt := range tests {
+		if caseBatchKey("run", tt.a) == caseBatchKey("run", tt.b) {
+			t.Errorf("%s: same key", tt.name)
+		}
+	}
+	if caseBatchKey("a", rows) == caseBatchKey("b", rows) {
+		t.Error("key does not depend on the run")
+	}
+}
+
+func TestSyncOutbox(t *testing.T) {
+	tests := []struct {
+		name        string
+		failCreate  func(n int, table string) bool // during the first attempt
+		conflict    bool                           // during the sync
+		wantPending int
+	}{
+		{
+			name:       "metric failed",
+			failCreate: func(n int, table string) bool { return table == "unittest_runs" },
+		},
+		{
+			// the first case batch is stored, the second fails; the retry
+			// replays the first batch key and must still store the second
+			name:       "second case batch failed",
+			failCreate: func(n int, table string) bool { return n == 2 },
+		},
+		{
+			name:        "conflict with rows missing",
+			failCreate:  func(n int, table string) bool { return n == 2 },
+			conflict:    true,
+			wantPending: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newStorageServer(t)
+			entry := testEntry("run-1", 2*caseUploadBatch)
+
+			srv.failCreate = tt.failCreate
+			if err := SynraxRunStorage(context.Background(), entry.Metric, entry.Cases); err == nil {
+				t.Fatal("first attempt did not fail")
+			}
+			dir := t.TempDir()
+			if _, err := WriteOutbox(dir, entry); err != nil {
+				t.Fatal(err)
+			}
+
+			srv.failCreate = nil
+			srv.conflict = tt.conflict
+			synced, pending, err := SyncOutbox(context.Background(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pending != tt.wantPending || synced != 1-tt.wantPending {
+				t.Fatalf("synced=%d pending=%d, want pending=%d", synced, pending, tt.wantPending)
+			}
+			left, _ := PendingOutbox(dir)
+			if len(left) != tt.wantPending {
+				t.Errorf("%d files left, want %d", len(left), tt.wantPending)
+			}
+			if tt.wantPending > 0 {
+				return
+			}
+			if got := srv.count("unittest_runs"); got != 1 {
+				t.Errorf("%d runs stored, want 1", got)
+			}
+			if got := srv.count("unittest_case_results"); got != len(entry.Cases) {
+				t.Errorf("%d case rows stored, want %d", got, len(entry.Cases))
+			}
+		})
+	}
+}
+
+func TestSynraxRunStorageCaseBatchFailed(t *testing.T) {
+	srv := newStorageServer(t)
+	entry := testEntry("run-3", 2*caseUploadBatch)
+	entry.Metric.TargetBranch = "main"
+	entry.Metric.CasesStored = true
+
+	srv.failCreate = func(n int, table string) bool { return n == 2 }
+	if err := SynraxRunStorage(context.Background(), entry.Metric, entry.Cases); err == nil {
+		t.Fatal("storage did not fail")
+	}
+	if got := srv.count("unittest_runs"); got != 0 {
+		t.Errorf("%d runs stored before their cases, want 0", got)
+	}
+	// the half-stored run must not be offered as a baseline
+	if _, found, err := SynraxBaselineCaller(context.Background(), "r", "main"); err != nil || found {
+		t.Errorf("baseline found=%v err=%v, want none", found, err)
+	}
+
+	srv.failCreate = nil
+	if err := SynraxRunStorage(context.Background(), entry.Metric, entry.Cases); err != nil {
+		t.Fatal(err)
+	}
+	report, found, err := SynraxBaselineCaller(context.Background(), "r", "main")
+	if err != nil || !found {
+		t.Fatalf("baseline found=%v err=%v after the retry", found, err)
+	}
+	if len(report.Results) != len(entry.Cases) {
+		t.Errorf("%d baseline results, want %d", len(report.Results), len(entry.Cases))
+	}
+}
+
+func TestSyncOutboxTwice(t *testing.T) {
+	srv := newStorageServer(t)
+	entry := testEntry("run-2", 3)
+	dir := t.TempDir()
+	for range 2 {
+		if _, err := WriteOutbox(dir, entry); err != nil {
+			t.Fatal(err)
+		}
+		if _, pending, err := SyncOutbox(context.Background(), dir); err != nil || pending != 0 {
+			t.Fatalf("pending=%d err=%v", pending, err)
+		}
+	}
+	if got := srv.count("unittest_runs"); got != 1 {
+		t.Errorf("%d runs stored, want 1", got)
+	}
+	if got := srv.count("unittest_case_results"); got != 3 {
+		t.Errorf("%d case rows stored, want 3", got)
+	}
+}
+
+func TestWriteOutboxRejectsPathIDs(t *testing.T) {
+	for _, id := range []string{"", "../x", "a/b", ".hidden"} {
+		if _, err := WriteOutbox(t.TempDir(), OutboxEntry{ID: id}); err == nil {
+			t.Errorf("id %q accepted", id)
+		}
+	}
+}
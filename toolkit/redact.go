@@ -0,0 +1,277 @@
+package toolkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Case results leave the machine when uploaded, so credentials are masked
+// and response bodies capped first. Local report files are not redacted.
+
+// maxUploadBody caps the response body of an uploaded case result.
+const maxUploadBody = 4096
+
+const redactedValue = "<redacted>"
+
+// sensitiveKeyParts mark JSON keys, headers and query parameters whose values
+// are masked, "access_token" or "X-Api-Key" example.
+var sensitiveKeyParts = []string{
+	"password", "passwd", "secret", "token", "authorization", "api_key", "apikey",
+	"cookie", "session", "credential", "private_key",
+}
+
+func isSensitiveKey(key string) bool {
+	k := strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(k, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaseRecords turns the results of a report into redacted upload rows.
+func CaseRecords(repoID string, targetBranch string, report UnittestReport) []CaseRecord {
+	now := time.Now().UTC()
+	records := make([]CaseRecord, 0, len(report.Results))
+	for _, r := range report.Results {
+		res, truncated := redactCaseResult(r)
+		records = append(records, CaseRecord{
+			RunID:              report.RunID,
+			RepoID:             repoID,
+			TargetBranch:       targetBranch,
+			Commit:             report.Commit,
+			CreatedAt:          now,
+			UnittestCaseResult: res,
+			BodyTruncated:      truncated,
+		})
+	}
+	return records
+}
+
+// redactCaseResult returns a copy of r safe to upload; the report keeps the
+// original values. Why and Error are free text that may quote the body, so
+// the secrets masked elsewhere are also scrubbed from them.
+func redactCaseResult(r UnittestCaseResult) (UnittestCaseResult, bool) {
+	red := &redactor{}
+	r.ExpectedContent = red.json(r.ExpectedContent)
+	if r.Request != nil {
+		req := *r.Request
+		req.URL = red.url(req.URL)
+		if len(req.Headers) > 0 {
+			req.Headers = make(map[string]string, len(r.Request.Headers))
+			for k, v := range r.Request.Headers {
+				if isSensitiveKey(k) && v != "" {
+					red.remember(v)
+					v = redactedValue
+				}
+				req.Headers[k] = v
+			}
+		}
+		if body, ok := red.json(req.Body).(map[string]any); ok {
+			req.Body = body
+		}
+		r.Request = &req
+	}
+
+	body := strings.TrimSpace(r.Body)
+	var parsed any
+	if body != "" && json.Unmarshal([]byte(body), &parsed) == nil {
+		if raw, err := json.Marshal(red.json(parsed)); err == nil {
+			body = string(raw)
+		}
+	}
+
+	if r.ContentDiff != nil {
+		d := *r.ContentDiff
+		if isSensitiveKey(lastPathKey(d.Path)) {
+			red.remember(unquoteJSON(d.Expected))
+			red.remember(unquoteJSON(d.Actual))
+			d.Expected, d.Actual = redactedValue, redactedValue
+		} else {
+			d.Expected, d.Actual = red.compactJSON(d.Expected), red.compactJSON(d.Actual)
+		}
+		r.ContentDiff = &d
+		if r.Failure == "content_mismatch" {
+			r.Why = ContentMismatchReason(&d)
+		}
+	}
+
+	r.Why = red.text(r.Why)
+	r.Error = red.text(r.Error)
+	if len(r.Attempts) > 0 {
+		attempts := make([]CaseAttempt, len(r.Attempts))
+		for i, a := range r.Attempts {
+			a.Error = red.text(a.Error)
+			attempts[i] = a
+		}
+		r.Attempts = attempts
+	}
+
+	body = red.text(body)
+	truncated := len(body) > maxUploadBody
+	if truncated {
+		cut := truncateUTF8(body, maxUploadBody)
+		body = cut + fmt.Sprintf("... (%d more bytes)", len(body)-len(cut))
+	}
+	r.Body = body
+	return r, truncated
+}
+
+// ContentMismatchReason is the why_failed text of a content_mismatch case.
+func ContentMismatchReason(d *ContentDiff) string {
+	if d == nil {
+		return "Response content did not match expected structure."
+	}
+	return fmt.Sprintf("Response content mismatch at %s (expected=%s got=%s).", d.Path, d.Expected, d.Actual)
+}
+
+// minSecretLen keeps short values such as "1" or "yes" from being scrubbed
+// out of every text they happen to appear in.
+const minSecretLen = 4
+
+// redactor masks sensitive values and remembers them, so text derived from
+// the same response can be scrubbed afterwards.
+type redactor struct {
+	secrets []string
+}
+
+func (red *redactor) remember(v string) {
+	if len(v) >= minSecretLen && v != redactedValue {
+		red.secrets = append(red.secrets, v)
+	}
+}
+
+// json copies a decoded JSON value with sensitive object keys masked.
+func (red *redactor) json(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, child := range t {
+			if isSensitiveKey(k) && child != nil {
+				red.rememberValue(child)
+				out[k] = redactedValue
+				continue
+			}
+			out[k] = red.json(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, child := range t {
+			out[i] = red.json(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// rememberValue records every scalar under a masked key, nested ones too.
+func (red *redactor) rememberValue(v any) {
+	switch t := v.(type) {
+	case string:
+		red.remember(t)
+	case map[string]any:
+		for _, child := range t {
+			red.rememberValue(child)
+		}
+	case []any:
+		for _, child := range t {
+			red.rememberValue(child)
+		}
+	case nil, bool:
+	default:
+		red.remember(fmt.Sprint(t))
+	}
+}
+
+// compactJSON redacts a compact JSON value of a ContentDiff. Values that are
+// not JSON, "<missing>" or "len>=2" example, are returned as they are.
+func (red *redactor) compactJSON(s string) string {
+	var v any
+	if json.Unmarshal([]byte(s), &v) != nil {
+		return s
+	}
+	raw, err := json.Marshal(red.json(v))
+	if err != nil {
+		return redactedValue
+	}
+	return string(raw)
+}
+
+// url masks sensitive query parameters and URL credentials. The mask has no
+// angle brackets, they would be percent-encoded.
+func (red *redactor) url(raw string) string {
+	const mask = "redacted"
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			red.remember(p)
+		}
+		u.User = url.User(mask)
+	}
+	q := u.Query()
+	changed := false
+	for k, vs := range q {
+		if isSensitiveKey(k) {
+			for _, v := range vs {
+				red.remember(v)
+			}
+			q.Set(k, mask)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// sensitiveAssignment matches `key=value`, `key: value` and `"key":"value"`
+// where key is sensitive, in free text such as error messages.
+var sensitiveAssignment = regexp.MustCompile(`(?i)("?[\w-]*(?:` + strings.Join(sensitiveKeyParts, "|") + `)[\w-]*"?\s*[:=]\s*)("[^"]*"|[^\s,;&}"]+)`)
+
+// text scrubs the remembered secrets and sensitive assignments from s.
+func (red *redactor) text(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, secret := range red.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return sensitiveAssignment.ReplaceAllStringFunc(s, func(m string) string {
+		sub := sensitiveAssignment.FindStringSubmatch(m)
+		if strings.HasPrefix(sub[2], `"`) {
+			return sub[1] + `"` + redactedValue + `"`
+		}
+		return sub[1] + redactedValue
+	})
+}
+
+// lastPathKey returns the last object key of a diff path, "token" for
+// "$.data.token" and "$.items[2].token".
+func lastPathKey(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "["); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+func unquoteJSON(s string) string {
+	var str string
+	if json.Unmarshal([]byte(s), &str) == nil {
+		return str
+	}
+	return s
+}
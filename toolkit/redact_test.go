@@ -0,0 +1,109 @@
+package toolkit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRedactCaseResult(t *testing.T) {
+	const secret = "eyJSECRET"
+	tests := []struct {
+		name string
+		in   UnittestCaseResult
+	}{
+		{
+			name: "leaf path",
+			in: UnittestCaseResult{
+				Failure:     "content_mismatch",
+				Body:        `{"access_token":"` + secret + `"}`,
+				ContentDiff: &ContentDiff{Path: "$.access_token", Expected: `"abc"`, Actual: `"` + secret + `"`},
+				Why:         `Response content mismatch at $.access_token (expected="abc" got="` + secret + `").`,
+			},
+		},
+		{
+			name: "parent path",
+			in: UnittestCaseResult{
+				Failure:     "content_mismatch",
+				Body:        `{"data":{"id":1,"token":"` + secret + `"}}`,
+				ContentDiff: &ContentDiff{Path: "$.data", Expected: `{"id":2}`, Actual: `{"id":1,"token":"` + secret + `"}`},
+				Why:         `Response content mismatch at $.data (expected={"id":2} got={"id":1,"token":"` + secret + `"}).`,
+			},
+		},
+		{
+			name: "status mismatch quoting the body",
+			in: UnittestCaseResult{
+				Failure: "status_mismatch",
+				Body:    `{"error":"bad","session":"` + secret + `"}`,
+				Why:     `Expected status in [200] but received 400. Response hint: session ` + secret,
+				Error:   "status mismatch (got=400 expected=[200])",
+			},
+		},
+		{
+			name: "error with a token assignment",
+			in: UnittestCaseResult{
+				Failure: "network_error",
+				Error:   `Get "https://api.test/x?access_token=` + secret + `": dial tcp: refused`,
+				Request: &CaseRequest{URL: "https://api.test/x?access_token=" + secret},
+			},
+		},
+		{
+			name: "attempt errors",
+			in: UnittestCaseResult{
+				Failure:  "timeout",
+				Request:  &CaseRequest{Headers: map[string]string{"X-Api-Key": secret}},
+				Attempts: []CaseAttempt{{Error: "key " + secret + " rejected"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := redactCaseResult(tt.in)
+			raw, err := json.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(raw), secret) {
+				t.Errorf("secret leaked: %s", raw)
+			}
+		})
+	}
+}
+
+func TestRedactCaseResultKeepsOriginal(t *testing.T) {
+	in := UnittestCaseResult{
+		Body:        `{"token":"abcdef"}`,
+		ContentDiff: &ContentDiff{Path: "$.token", Expected: `"x"`, Actual: `"abcdef"`},
+		Request:     &CaseRequest{Headers: map[string]string{"Authorization": "Bearer abcdef"}},
+	}
+	redactCaseResult(in)
+	if in.ContentDiff.Actual != `"abcdef"` || in.Request.Headers["Authorization"] != "Bearer abcdef" {
+		t.Errorf("input modified: %+v %+v", in.ContentDiff, in.Request)
+	}
+}
+
+func TestRedactCaseResultTruncatesAtRune(t *testing.T) {
+	body := strings.Repeat("é", maxUploadBody) // two bytes each
+	out, truncated := redactCaseResult(UnittestCaseResult{Body: body})
+	if !truncated {
+		t.Fatal("want truncated")
+	}
+	if !utf8.ValidString(out.Body) {
+		t.Errorf("body is not valid UTF-8 after truncation")
+	}
+	kept := out.Body[:strings.Index(out.Body, "...")]
+	if len(kept) > maxUploadBody {
+		t.Errorf("kept %d bytes, cap is %d", len(kept), maxUploadBody)
+	}
+}
+
+func TestContentMismatchReason(t *testing.T) {
+	got := ContentMismatchReason(&ContentDiff{Path: "$.id", Expected: "1", Actual: "2"})
+	if want := "Response content mismatch at $.id (expected=1 got=2)."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := ContentMismatchReason(nil); got == "" {
+		t.Error("empty reason for nil diff")
+	}
+}
@@ -2,22 +2,31 @@ package toolkit
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
 )
 
 // This module calls our APIs to load the EndpointModule, Test Spec, and Application Config
 // using internal tools by calling our server
 
+// ErrUnavailable wraps errors where the Synrax API could not be reached or
+// answered 5xx, as opposed to rejecting the request.
+var ErrUnavailable = errors.New("synrax api unavailable")
+
 // call test spec JSON
-func SynraxSpecCaller(docs string, cfg UnittestConfig, repoID string) (TestSpec, error) {
+func SynraxSpecCaller(ctx context.Context, docs string, cfg UnittestConfig, repoID string) (TestSpec, error) {
 	BASE := os.Getenv("SYNRAX_API_BASE_URL")
 	if strings.TrimSpace(BASE) == "" {
 		return TestSpec{}, fmt.Errorf("API_BASE_URL is empty")
@@ -31,7 +40,8 @@ func SynraxSpecCaller(docs string, cfg UnittestConfig, repoID string) (TestSpec,
 	}
 
 	URL := fmt.Sprintf("%s/ai/test_spec?repo_id=%s", BASE, repoID)
-	log.Printf("toolkit.spec: start url=%s docs_bytes=%d config_base=%s auth_token_present=%t", URL, len(docs), cfg.BaseURL, strings.TrimSpace(cfg.AuthToken) != "")
+	slog.Info("toolkit.spec: start", "repo_id", repoID, "docs_bytes", len(docs))
+	slog.Debug("toolkit.spec: request", "url", URL, "config_base", cfg.BaseURL, "auth_token_present", strings.TrimSpace(cfg.AuthToken) != "")
 
 	payload := struct {
 		Documentation string         `json:"documentation"`
@@ -41,40 +51,43 @@ func SynraxSpecCaller(docs string, cfg UnittestConfig, repoID string) (TestSpec,
 		Config:        cfg,
 	}
 
-	resp, body, err := postJSON(URL, payload)
+	resp, body, err := postJSON(ctx, URL, payload)
 	if err != nil {
-		log.Printf("toolkit.spec: request failed url=%s error=%v", URL, err)
+		slog.Error("toolkit.spec: request failed", "url", URL, "error", err)
 		return TestSpec{}, err
 	}
-	log.Printf("toolkit.spec: response status=%d", resp.StatusCode)
+	slog.Debug("toolkit.spec: response", "status", resp.StatusCode, "bytes", len(body))
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Printf("toolkit.spec: non-2xx status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+		slog.Error("toolkit.spec: non-2xx response", "status", resp.StatusCode)
+		slog.Debug("toolkit.spec: response body", "body", truncateForLog(body, 500))
 		return TestSpec{}, fmt.Errorf("test_spec request failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
 	}
 
 	spec, err := decodeSpecBody(body)
 	if err != nil {
-		log.Printf("toolkit.spec: decode failed error=%v body=%s", err, truncateForLog(body, 300))
+		slog.Error("toolkit.spec: decode failed", "error", err)
+		slog.Debug("toolkit.spec: response body", "body", truncateForLog(body, 300))
 		return TestSpec{}, err
 	}
-	log.Printf("toolkit.spec: response body bytes=%d", len(body))
 	if len(spec.Endpoints) == 0 {
-		log.Printf("toolkit.spec: empty endpoints response body=%s", truncateForLog(body, 2000))
+		slog.Warn("toolkit.spec: no endpoints in response")
+		slog.Debug("toolkit.spec: response body", "body", truncateForLog(body, 2000))
 	}
-	log.Printf("toolkit.spec: decoded endpoints=%d", len(spec.Endpoints))
+	slog.Info("toolkit.spec: decoded", "endpoints", len(spec.Endpoints))
 
 	return spec, nil
 }
 
 // DB interaction to check on user's config
-func SynraxConfigCaller(repo_id string) (UnittestConfig, error) {
+func SynraxConfigCaller(ctx context.Context, repo_id string) (UnittestConfig, error) {
 	// we need to fetch config that our program requires to run internally
 
 	BASE := os.Getenv("SYNRAX_API_BASE_URL")
 
 	URL := fmt.Sprintf("%s/db/read?table=global_config", BASE)
-	log.Printf("toolkit.config: start url=%s repo_id=%s", URL, repo_id)
+	slog.Info("toolkit.config: start", "repo_id", repo_id)
+	slog.Debug("toolkit.config: request", "url", URL)
 
 	payload := struct {
 		Filter map[string]string `json:"filter"`
@@ -84,40 +97,47 @@ func SynraxConfigCaller(repo_id string) (UnittestConfig, error) {
 		},
 	}
 
-	resp, body, err := postJSON(URL, payload)
+	resp, body, err := postJSON(ctx, URL, payload)
 	if err != nil {
-		log.Printf("toolkit.config: request failed url=%s error=%v", URL, err)
+		slog.Error("toolkit.config: request failed", "url", URL, "error", err)
 		return UnittestConfig{}, err
 	}
-	log.Printf("toolkit.config: response status=%d", resp.StatusCode)
+	slog.Debug("toolkit.config: response", "status", resp.StatusCode, "bytes", len(body))
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Printf("toolkit.config: non-2xx status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
-		return UnittestConfig{}, fmt.Errorf("config request failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+		slog.Error("toolkit.config: non-2xx response", "status", resp.StatusCode)
+		slog.Debug("toolkit.config: response body", "body", truncateForLog(body, 500))
+		err := fmt.Errorf("config request failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return UnittestConfig{}, err
 	}
 
 	config, err := decodeConfigBody(body)
 	if err != nil {
 		if errors.Is(err, errConfigNotFound) {
-			log.Printf("toolkit.config: config missing repo_id=%s body=%s", repo_id, truncateForLog(body, 500))
+			slog.Error("toolkit.config: config missing", "repo_id", repo_id)
+			slog.Debug("toolkit.config: response body", "body", truncateForLog(body, 500))
 			return UnittestConfig{}, fmt.Errorf("repo_id=%s: %w", repo_id, errConfigNotFound)
 		}
-		log.Printf("toolkit.config: decode failed error=%v body=%s", err, truncateForLog(body, 500))
+		slog.Error("toolkit.config: decode failed", "error", err)
+		slog.Debug("toolkit.config: response body", "body", truncateForLog(body, 500))
 		return UnittestConfig{}, err
 	}
 
-	log.Printf("toolkit.config: decoded base=%s auth_token_present=%t", config.BaseURL, config.AuthToken != "")
+	slog.Info("toolkit.config: decoded", "base", config.BaseURL, "auth_token_present", config.AuthToken != "")
 
 	return config, nil
 }
 
 // Authenticate OIDC
-func SynraxOIDCCaller(repo_id string, OIDCtoken string) (bool, error) {
+func SynraxOIDCCaller(ctx context.Context, repo_id string, OIDCtoken string) (bool, error) {
 	key := os.Getenv("INTERNAL_API_KEY")
 	BASE := os.Getenv("SYNRAX_API_BASE_URL")
 	URL := fmt.Sprintf("%s/github/oidc_validate?oidc_token=%s&repo_id=%s", BASE, OIDCtoken, repo_id)
 
-	request, err := http.NewRequest(http.MethodGet, URL, nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
 	if err != nil {
 		return false, err
 	}
@@ -126,15 +146,18 @@ func SynraxOIDCCaller(repo_id string, OIDCtoken string) (bool, error) {
 	client := &http.Client{}
 	resp, err := client.Do(request)
 	if err != nil {
-		return false, err
+		return false, fmt.Errorf("%w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return false, err
+		return false, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	slog.Debug("toolkit.oidc: response", "status", resp.StatusCode, "body", truncateForLog(body, 500))
+	if resp.StatusCode >= 500 {
+		return false, fmt.Errorf("%w: oidc validation status=%d", ErrUnavailable, resp.StatusCode)
 	}
-	log.Printf("%s\n", body)
 
 	var oidc OIDCResp
 	if err := json.Unmarshal(body, &oidc); err != nil {
@@ -148,27 +171,49 @@ func SynraxOIDCCaller(repo_id string, OIDCtoken string) (bool, error) {
 	return true, nil // case: OIDC Token is valid
 }
 
-func SynraxReportStorage(repoID string, targetBranch string, report UnittestReport) error {
+// Storage creates are safe to repeat. Each names the unique key of its table
+// in on_conflict, so the API inserts only rows it does not have yet; a retry
+// after a lost response stores nothing twice.
+const (
+	runConflictKey  = "id"
+	caseConflictKey = "run_id,endpoint,method,test_id,as"
+)
 
-	metrics, err := ReportMetrics(repoID, targetBranch, report)
-	if err != nil {
-		return err
+// SynraxRunStorage stores the case rows of a run and then its metric. The
+// metric goes last because CasesStored makes the run a baseline candidate:
+// a failed case batch leaves no metric behind, and a retry stores both.
+func SynraxRunStorage(ctx context.Context, metric ReportMetric, records []CaseRecord) error {
+	if len(records) > 0 {
+		if err := SynraxCaseStorage(ctx, metric.ID, records); err != nil {
+			return err
+		}
 	}
+	return SynraxMetricStorage(ctx, metric)
+}
 
-	base := os.Getenv("SYNRAX_API_BASE_URL")
-	URL := fmt.Sprintf("%s/db/create?table=unittest_runs", base)
+// SynraxMetricStorage stores one run metric. The metric id is also sent as the
+// Idempotency-Key. A 409 means the unique key on id already holds the run.
+func SynraxMetricStorage(ctx context.Context, metric ReportMetric) error {
+	if metric.ID == "" {
+		return fmt.Errorf("report storage: metric has no id")
+	}
+	URL := createURL("unittest_runs", runConflictKey)
 
 	payload := struct {
 		Schema ReportMetric `json:"schema"`
 	}{
-		Schema: metrics,
+		Schema: metric,
 	}
 
-	resp, body, err := postJSON(URL, payload)
+	resp, body, err := postJSONIdempotent(ctx, URL, metric.ID, payload)
 	if err != nil {
 		return err
 	}
 
+	if resp.StatusCode == http.StatusConflict {
+		slog.Info("toolkit.storage: run already stored", "id", metric.ID)
+		return nil
+	}
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return fmt.Errorf("report storage failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
 	}
@@ -176,6 +221,195 @@ func SynraxReportStorage(repoID string, targetBranch string, report UnittestRepo
 	return nil
 }
 
+// caseUploadBatch is the number of case rows posted per request.
+const caseUploadBatch = 100
+
+// SynraxCaseStorage uploads case rows of the run to unittest_case_results in
+// batches. Rows carry the run id of the metric SynraxRunStorage stores after
+// them; see CaseRecords for the redaction. A 409 counts as stored only once
+// reading the run back shows every row of the batch.
+func SynraxCaseStorage(ctx context.Context, runID string, records []CaseRecord) error {
+	if runID == "" {
+		return fmt.Errorf("case storage: report has no run id")
+	}
+	URL := createURL("unittest_case_results", caseConflictKey)
+
+	for start := 0; start < len(records); start += caseUploadBatch {
+		batch := records[start:min(start+caseUploadBatch, len(records))]
+		payload := struct {
+			Schema []CaseRecord `json:"schema"`
+		}{
+			Schema: batch,
+		}
+		resp, body, err := postJSONIdempotent(ctx, URL, caseBatchKey(runID, batch), payload)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusConflict {
+			stored, err := casesStored(ctx, runID, batch)
+			if err != nil {
+				return fmt.Errorf("case storage conflict, verifying rows: %w", err)
+			}
+			if !stored {
+				return fmt.Errorf("case storage conflict with rows missing, body=%s", truncateForLog(body, 500))
+			}
+			slog.Debug("toolkit.cases: batch already stored", "run_id", runID, "rows", len(batch))
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("case storage failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+		}
+		slog.Debug("toolkit.cases: batch stored", "run_id", runID, "rows", len(batch))
+	}
+	slog.Info("toolkit.cases: stored", "run_id", runID, "rows", len(records))
+	return nil
+}
+
+// casesStored reports whether every row of batch is stored for runID.
+func casesStored(ctx context.Context, runID string, batch []CaseRecord) (bool, error) {
+	rows, err := readRunCases(ctx, runID)
+	if err != nil {
+		return false, err
+	}
+	have := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		have[caseRowKey(r)] = true
+	}
+	for _, r := range batch {
+		if !have[caseRowKey(r)] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readRunCases returns the stored case rows of runID.
+func readRunCases(ctx context.Context, runID string) ([]CaseRecord, error) {
+	base := os.Getenv("SYNRAX_API_BASE_URL")
+	URL := fmt.Sprintf("%s/db/read?table=unittest_case_results", base)
+	payload := struct {
+		Filter map[string]any `json:"filter"`
+	}{
+		Filter: map[string]any{"run_id": runID},
+	}
+	resp, body, err := postJSON(ctx, URL, payload)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("read failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+	}
+	rows, err := decodeRows[CaseRecord](body)
+	if err != nil {
+		return nil, err
+	}
+	own := rows[:0]
+	for _, r := range rows {
+		if r.RunID == runID {
+			own = append(own, r)
+		}
+	}
+	return own, nil
+}
+
+// caseRowKey identifies a row within its run, the columns of caseConflictKey.
+func caseRowKey(r CaseRecord) string {
+	return strings.Join([]string{r.Endpoint, r.Method, r.TestID, r.As}, "\x00")
+}
+
+func createURL(table string, conflictKey string) string {
+	base := os.Getenv("SYNRAX_API_BASE_URL")
+	return fmt.Sprintf("%s/db/create?table=%s&on_conflict=%s", base, url.QueryEscape(table), url.QueryEscape(conflictKey))
+}
+
+// caseBatchKey is the Idempotency-Key of a batch, derived from the rows it
+// holds so two batches with different rows never share a key.
+func caseBatchKey(runID string, batch []CaseRecord) string {
+	keys := make([]string, len(batch))
+	for i, r := range batch {
+		keys[i] = caseRowKey(r)
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return runID + "/cases/" + hex.EncodeToString(sum[:16])
+}
+
+// Fetch the latest stored run of the branch that uploaded its case rows, used
+// as a baseline. Only the newest run is requested; its outcomes are read from
+// unittest_case_results. found is false when the branch has no such run yet.
+func SynraxBaselineCaller(ctx context.Context, repoID string, targetBranch string) (UnittestReport, bool, error) {
+	base := os.Getenv("SYNRAX_API_BASE_URL")
+	URL := fmt.Sprintf("%s/db/read?table=unittest_runs", base)
+	slog.Info("toolkit.baseline: start", "repo_id", repoID, "target_branch", targetBranch)
+	slog.Debug("toolkit.baseline: request", "url", URL)
+
+	payload := struct {
+		Filter map[string]any `json:"filter"`
+		Order  string         `json:"order"`
+		Limit  int            `json:"limit"`
+	}{
+		Filter: map[string]any{
+			"repo_id":       repoID,
+			"target_branch": targetBranch,
+			"cases_stored":  true,
+		},
+		Order: "-created_at",
+		Limit: 1,
+	}
+
+	resp, body, err := postJSON(ctx, URL, payload)
+	if err != nil {
+		slog.Error("toolkit.baseline: request failed", "url", URL, "error", err)
+		return UnittestReport{}, false, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return UnittestReport{}, false, fmt.Errorf("baseline request failed with status=%d body=%s", resp.StatusCode, truncateForLog(body, 500))
+	}
+
+	runs, err := decodeRunsBody(body)
+	if err != nil {
+		slog.Error("toolkit.baseline: decode failed", "error", err)
+		slog.Debug("toolkit.baseline: response body", "body", truncateForLog(body, 500))
+		return UnittestReport{}, false, err
+	}
+	var latest *ReportMetric
+	for i := range runs {
+		// the filter is checked again in case the API ignores part of it
+		if !runs[i].CasesStored || runs[i].ID == "" {
+			continue
+		}
+		if latest == nil || runs[i].CreatedAt.After(latest.CreatedAt) {
+			latest = &runs[i]
+		}
+	}
+	if latest == nil {
+		slog.Info("toolkit.baseline: no previous run with case rows", "repo_id", repoID, "target_branch", targetBranch)
+		return UnittestReport{}, false, nil
+	}
+
+	rows, err := readRunCases(ctx, latest.ID)
+	if err != nil {
+		return UnittestReport{}, false, fmt.Errorf("baseline cases of run %s: %w", latest.ID, err)
+	}
+	if len(rows) == 0 {
+		// the metric is stored but its case rows are not, e.g. still in an outbox
+		slog.Warn("toolkit.baseline: run has no case rows", "id", latest.ID)
+		return UnittestReport{}, false, nil
+	}
+	slog.Info("toolkit.baseline: using run", "id", latest.ID, "created_at", latest.CreatedAt.Format(time.RFC3339), "cases", len(rows), "filtered", latest.Filtered)
+
+	report := UnittestReport{
+		RunID:    latest.ID,
+		Filtered: latest.Filtered,
+		Commit:   latest.Commit,
+		SpecHash: latest.SpecHash,
+	}
+	for _, r := range rows {
+		report.Results = append(report.Results, r.UnittestCaseResult)
+	}
+	return report, true, nil
+}
+
 // ---------- helpers
 
 var errConfigNotFound = errors.New("config not found for repo")
@@ -268,6 +502,39 @@ func decodeSpecBody(body []byte) (TestSpec, error) {
 	return TestSpec{}, fmt.Errorf("could not find test spec payload (expected top-level or response wrapper)")
 }
 
+// decodeRunsBody accepts a bare run, a list of runs or either inside a response wrapper.
+func decodeRunsBody(body []byte) ([]ReportMetric, error) {
+	runs, err := decodeRows[ReportMetric](body)
+	if err != nil {
+		return nil, fmt.Errorf("could not find stored runs payload: %w", err)
+	}
+	return runs, nil
+}
+
+// decodeRows accepts a bare row, a list of rows or either inside a response wrapper.
+func decodeRows[T any](body []byte) ([]T, error) {
+	var wrapper struct {
+		Response json.RawMessage `json:"response"`
+	}
+	raw := bytes.TrimSpace(body)
+	if err := json.Unmarshal(raw, &wrapper); err == nil && len(wrapper.Response) > 0 {
+		raw = bytes.TrimSpace(wrapper.Response)
+	}
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var list []T
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var single T
+	if err := json.Unmarshal(raw, &single); err != nil {
+		return nil, err
+	}
+	return []T{single}, nil
+}
+
 func findMapWithConfigKeys(v any) (map[string]any, bool) {
 	switch t := v.(type) {
 	case map[string]any:
@@ -306,30 +573,39 @@ func truncateForLog(body []byte, max int) string {
 	return string(body[:max]) + "..."
 }
 
-func postJSON(url string, payload any) (*http.Response, []byte, error) {
+func postJSON(ctx context.Context, url string, payload any) (*http.Response, []byte, error) {
+	return postJSONIdempotent(ctx, url, "", payload)
+}
+
+// postJSONIdempotent is postJSON with an Idempotency-Key header when key is
+// set, so the API can drop a create it has already applied.
+func postJSONIdempotent(ctx context.Context, url string, key string, payload any) (*http.Response, []byte, error) {
 	raw, err := json.Marshal(payload)
 	if err != nil {
 		return nil, nil, fmt.Errorf("marshal payload: %w", err)
 	}
-	key := os.Getenv("INTERNAL_API_KEY")
+	apiKey := os.Getenv("INTERNAL_API_KEY")
 	requestPayload := bytes.NewReader(raw)
-	request, err := http.NewRequest(http.MethodPost, url, requestPayload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestPayload)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	request.Header.Set("Authorization", "Bearer "+key)
+	request.Header.Set("Authorization", "Bearer "+apiKey)
+	if key != "" {
+		request.Header.Set("Idempotency-Key", key)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(request)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
 	}
 
 	return resp, body, nil
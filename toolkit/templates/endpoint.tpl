@@ -1,12 +1,29 @@
-# Endpoint: {{ .Name }}
 
-### Success: {{ .Passed }}
+<details>
+<summary><b>{{ .Method }} {{ .Name }}</b> · <code>{{ .TestID }}</code> · {{ .Passed }}{{ if .Failure }} ({{ .Failure }}){{ end }}</summary>
 
-**Method:** {{ .Method }}
-**Test ID** `{{ .TestID }}`
+{{ if .Why }}**Why:** {{ .Why }}
+{{ end }}{{ if .Error }}**Error:** `{{ cell .Error }}`
+{{ end }}{{ if .As }}**Identity:** `{{ .As }}`
+{{ end }}{{ if .Attempts }}**Attempts:** {{ .Attempts }}
+{{ end }}
+{{ with .Request }}**Request**
 
-**Expected Outputs:** {{ .ExpectedStatus }}
-**Booted Code:** {{ .Status }}
+```http
+{{ .Method }} {{ .URL }}{{ range $k, $v := .Headers }}
+{{ $k }}: {{ $v }}{{ end }}
+```
+{{ end }}{{ if .RequestBody }}{{ .RequestBody }}{{ end }}
+| | Expected | Actual |
+|---|---|---|
+| Status | {{ if .ExpectedStatus }}{{ .ExpectedStatus }}{{ else }}2xx{{ end }} | {{ if .Status }}{{ .Status }}{{ else }}no response{{ end }} |
+{{ with .Diff }}| `{{ cell .Path }}` | `{{ cell .Expected }}` | `{{ cell .Actual }}` |
+{{ end }}{{ if .Status }}| Latency | | {{ .LatencyMS }} ms |
+{{ end }}{{ if .ExpectedBody }}
+**Expected content**
 
-**Booted Output:**
-{{ .Body }}
\ No newline at end of file
+{{ .ExpectedBody }}{{ end }}{{ if .Body }}
+**Response body**
+
+{{ .Body }}{{ end }}
+</details>
@@ -0,0 +1,7 @@
+
+## Endpoints
+
+| Endpoint | Method | Cases | Passed | Failed | Flaky | Errored | Skipped |
+|----------|--------|------:|-------:|-------:|------:|--------:|--------:|
+{{ range . }}| `{{ cell .Name }}` | {{ .Method }} | {{ .Cases }} | {{ .Passed }} | {{ .Failed }} | {{ .Flaky }} | {{ .Errored }} | {{ .Skipped }} |
+{{ end }}
@@ -1,3 +1,17 @@
-**Endpoints tested**: {{ .Total }}
-**Endpoints passed**: {{ .Passed }}
-**Endpoints failed**: {{ .Failed }}
+# Synrax API test report
+
+{{ if .Interrupted }}> **Run interrupted**: partial results, remaining cases were skipped.
+
+{{ end }}{{ if .RerunOf }}> Rerun of the cases that did not pass in `{{ .RerunOf }}`.
+
+{{ end }}{{ if .AllPassed }}**All {{ .Total }} cases passed.**{{ if .Flaky }} {{ .Flaky }} of them only after a retry.{{ end }}{{ else }}**{{ .Failed }} failed, {{ .Errored }} errored** out of {{ .Total }} cases.{{ end }}
+
+| Total | Passed | Failed | Flaky | Errored | Skipped | Hook failures |
+|------:|-------:|-------:|------:|--------:|--------:|--------------:|
+| {{ .Total }} | {{ .Passed }} | {{ .Failed }} | {{ .Flaky }} | {{ .Errored }} | {{ .Skipped }} | {{ .HookFailures }} |
+{{ if .Fixed }}
+**Fixed since previous run**: {{ .Fixed }}
+{{ end }}{{ with .Baseline }}
+**Compared with** `{{ .Source }}`: {{ .NewFailures }} new failures, {{ .Fixed }} fixed, {{ .StillFailing }} still failing, {{ .NewTests }} new tests ({{ .NewTestFailures }} failing), {{ .RemovedTests }} removed tests{{ if .Unknown }}, {{ .Unknown }} not in the filtered baseline{{ end }}
+{{ range .Cases }}{{ if eq .Change "new_failure" }}- New failure: {{ .Method }} {{ .Endpoint }} `{{ .TestID }}`
+{{ end }}{{ end }}{{ end }}
@@ -0,0 +1,10 @@
+
+<details>
+<summary><b>{{ .Stage }}</b> ({{ .Scope }}) · <code>{{ .HookID }}</code>{{ if .Failure }} · {{ .Failure }}{{ end }}</summary>
+
+**Request:** {{ .Method }} {{ .Endpoint }}{{ if .TestID }}
+**Wrapped Test ID** `{{ .TestID }}`{{ end }}
+**Status:** {{ .Status }}
+**Error:** `{{ cell .Error }}`
+
+</details>
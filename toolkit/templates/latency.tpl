@@ -0,0 +1,7 @@
+
+## Latency (ms)
+
+| Endpoint | Method | Min | Avg | p50 | p95 | Max |
+|----------|--------|----:|----:|----:|----:|----:|
+{{ range . }}| `{{ cell .Name }}` | {{ .Method }} | {{ .MinMS }} | {{ .AvgMS }} | {{ .P50MS }} | {{ .P95MS }} | {{ .MaxMS }} |
+{{ end }}
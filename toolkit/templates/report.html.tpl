@@ -0,0 +1,156 @@
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Synrax API test report</title>
+<style>
+:root { --pass: #1a7f37; --fail: #cf222e; --err: #9a6700; --skip: #6e7781; --flaky: #8250df; --line: #d0d7de; }
+body { font: 14px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; padding: 24px 32px; color: #1f2328; }
+h1 { margin: 0 0 4px; font-size: 24px; }
+h2 { margin: 32px 0 12px; font-size: 18px; border-bottom: 1px solid var(--line); padding-bottom: 4px; }
+.muted { color: #656d76; }
+.banner { padding: 8px 12px; border-radius: 6px; background: #fff8c5; margin: 12px 0; }
+.cards { display: flex; gap: 12px; flex-wrap: wrap; margin: 16px 0; }
+.card { border: 1px solid var(--line); border-radius: 6px; padding: 8px 16px; min-width: 90px; }
+.card b { display: block; font-size: 22px; }
+.badge { display: inline-block; padding: 0 8px; border-radius: 10px; color: #fff; font-size: 12px; font-weight: 600; white-space: nowrap; }
+.passed { background: var(--pass); } .failed { background: var(--fail); } .errored { background: var(--err); }
+.skipped { background: var(--skip); } .flaky { background: var(--flaky); }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid var(--line); padding: 6px 8px; text-align: left; vertical-align: top; }
+th.sortable { cursor: pointer; user-select: none; } th.sortable::after { content: " ↕"; color: #8c959f; }
+td.num, th.num { text-align: right; }
+tbody.case > tr.row { cursor: pointer; } tbody.case > tr.row:hover { background: #f6f8fa; }
+tr.detail { display: none; } tbody.case.open > tr.detail { display: table-row; }
+tr.detail > td { background: #f6f8fa; padding: 12px 16px; }
+.controls { display: flex; gap: 8px; margin-bottom: 8px; }
+.controls input { flex: 1; padding: 4px 8px; } .controls select { padding: 4px 8px; }
+pre { background: #fff; border: 1px solid var(--line); border-radius: 6px; padding: 8px; overflow: auto; max-height: 400px; margin: 4px 0 12px; }
+.cols { display: grid; grid-template-columns: 1fr 1fr; gap: 12px; }
+.diff .add { background: #dafbe1; display: block; } .diff .del { background: #ffebe9; display: block; } .diff .ctx { display: block; }
+.hist { display: flex; align-items: flex-end; gap: 8px; height: 180px; border-bottom: 1px solid var(--line); padding-top: 16px; }
+.hist .bar { flex: 1; display: flex; flex-direction: column; justify-content: flex-end; align-items: center; height: 100%; }
+.hist .fill { width: 100%; background: #54aeff; border-radius: 4px 4px 0 0; min-height: 1px; }
+.hist .count { font-size: 12px; } .labels { display: flex; gap: 8px; } .labels span { flex: 1; text-align: center; font-size: 12px; color: #656d76; }
+</style>
+</head>
+<body>
+<h1>Synrax API test report</h1>
+<div class="muted">Generated {{ .Generated }}</div>
+{{ with .Global }}{{ if .Interrupted }}<div class="banner"><b>Run interrupted:</b> partial results, remaining cases were skipped.</div>{{ end }}
+{{ if .RerunOf }}<div class="banner">Rerun of the cases that did not pass in <code>{{ .RerunOf }}</code>.</div>{{ end }}
+<div class="cards">
+<div class="card"><b>{{ .Total }}</b>total</div>
+<div class="card"><b style="color: var(--pass)">{{ .Passed }}</b>passed</div>
+<div class="card"><b style="color: var(--fail)">{{ .Failed }}</b>failed</div>
+<div class="card"><b style="color: var(--flaky)">{{ .Flaky }}</b>flaky</div>
+<div class="card"><b style="color: var(--err)">{{ .Errored }}</b>errored</div>
+<div class="card"><b style="color: var(--skip)">{{ .Skipped }}</b>skipped</div>
+<div class="card"><b>{{ .HookFailures }}</b>hook failures</div>
+{{ if .Fixed }}<div class="card"><b style="color: var(--pass)">{{ .Fixed }}</b>fixed</div>{{ end }}
+</div>
+{{ with .Baseline }}<p>Compared with <code>{{ .Source }}</code>: {{ .NewFailures }} new failures, {{ .Fixed }} fixed, {{ .StillFailing }} still failing, {{ .NewTests }} new tests ({{ .NewTestFailures }} failing), {{ .RemovedTests }} removed tests{{ if .Unknown }}, {{ .Unknown }} not in the filtered baseline{{ end }}.</p>{{ end }}
+{{ end }}
+
+<h2>Endpoints</h2>
+<table class="sortable-table">
+<thead><tr>
+<th class="sortable">Endpoint</th><th class="sortable">Method</th><th class="sortable">Status</th>
+<th class="sortable num">Cases</th><th class="sortable num">Passed</th><th class="sortable num">Failed</th>
+<th class="sortable num">Flaky</th><th class="sortable num">Errored</th><th class="sortable num">Skipped</th>
+<th class="sortable num">Avg ms</th><th class="sortable num">p95 ms</th>
+</tr></thead>
+{{ range .Endpoints }}<tbody><tr>
+<td><code>{{ .Name }}</code></td><td>{{ .Method }}</td>
+<td>{{ if or .Failed .Errored }}<span class="badge failed">failing</span>{{ else if .Flaky }}<span class="badge flaky">flaky</span>{{ else if eq .Skipped .Cases }}<span class="badge skipped">skipped</span>{{ else }}<span class="badge passed">passing</span>{{ end }}</td>
+<td class="num">{{ .Cases }}</td><td class="num">{{ .Passed }}</td><td class="num">{{ .Failed }}</td>
+<td class="num">{{ .Flaky }}</td><td class="num">{{ .Errored }}</td><td class="num">{{ .Skipped }}</td>
+<td class="num">{{ .AvgMS }}</td><td class="num">{{ .P95MS }}</td>
+</tr></tbody>{{ end }}
+</table>
+
+<h2>Cases</h2>
+<div class="controls">
+<input id="filter" type="search" placeholder="Filter by endpoint, test id, failure…">
+<select id="outcome">
+<option value="">All outcomes</option><option>passed</option><option>failed</option><option>flaky</option><option>errored</option><option>skipped</option>
+</select>
+</div>
+<table class="sortable-table" id="cases">
+<thead><tr>
+<th class="sortable">Endpoint</th><th class="sortable">Method</th><th class="sortable">Test ID</th><th class="sortable">Outcome</th>
+<th class="sortable num">Status</th><th>Expected</th><th class="sortable num">Latency ms</th><th class="sortable">Failure</th>
+</tr></thead>
+{{ range .Cases }}<tbody class="case" data-outcome="{{ .Outcome }}"><tr class="row">
+<td><code>{{ .Endpoint }}</code></td><td>{{ .Method }}</td><td><code>{{ .TestID }}</code></td>
+<td><span class="badge {{ lower .Outcome }}">{{ .Outcome }}</span></td>
+<td class="num">{{ if .Status }}{{ .Status }}{{ else }}–{{ end }}</td><td>{{ .ExpectedStatus }}</td>
+<td class="num">{{ .LatencyMS }}</td><td>{{ .Failure }}</td>
+</tr><tr class="detail"><td colspan="8">
+{{ if .Why }}<p><b>Why:</b> {{ .Why }}</p>{{ end }}
+{{ if .Error }}<p><b>Error:</b> <code>{{ .Error }}</code></p>{{ end }}
+{{ if .As }}<p><b>Identity:</b> <code>{{ .As }}</code></p>{{ end }}
+{{ with .ContentDiff }}<p><b>First difference</b> at <code>{{ .Path }}</code>: expected <code>{{ .Expected }}</code>, got <code>{{ .Actual }}</code></p>{{ end }}
+{{ with .Request }}<b>Request</b>
+<pre>{{ .Method }} {{ .URL }}{{ range $k, $v := .Headers }}
+{{ $k }}: {{ $v }}{{ end }}</pre>{{ end }}
+{{ if .RequestBody }}<pre>{{ .RequestBody }}</pre>{{ end }}
+{{ if .Diff }}<b>Expected vs actual body</b> <span class="muted">(− only expected, + only actual)</span>
+<pre class="diff">{{ range .Diff }}{{ if eq .Op "+" }}<span class="add">+ {{ .Text }}</span>{{ else if eq .Op "-" }}<span class="del">− {{ .Text }}</span>{{ else }}<span class="ctx">  {{ .Text }}</span>{{ end }}{{ end }}</pre>
+{{ else if or .ExpectedBody .ActualBody }}<div class="cols">
+<div><b>Expected content</b><pre>{{ if .ExpectedBody }}{{ .ExpectedBody }}{{ else }}(not asserted){{ end }}</pre></div>
+<div><b>Response body</b><pre>{{ if .ActualBody }}{{ .ActualBody }}{{ else }}(empty){{ end }}</pre></div>
+</div>{{ end }}
+{{ if .Attempts }}<b>Attempts</b>
+<table><thead><tr><th>#</th><th class="num">Status</th><th>Failure</th><th class="num">Latency ms</th></tr></thead>
+<tbody>{{ range $i, $a := .Attempts }}<tr><td>{{ $i }}</td><td class="num">{{ $a.Status }}</td><td>{{ $a.Failure }}</td><td class="num">{{ $a.LatencyMS }}</td></tr>{{ end }}</tbody></table>{{ end }}
+</td></tr></tbody>{{ end }}
+</table>
+
+{{ if .Histogram }}<h2>Latency</h2>
+<div class="hist">{{ range .Histogram }}<div class="bar" title="{{ .Label }} ms: {{ .Count }} cases"><span class="count">{{ .Count }}</span><div class="fill" style="height: {{ .Height }}%"></div></div>{{ end }}</div>
+<div class="labels">{{ range .Histogram }}<span>{{ .Label }} ms</span>{{ end }}</div>{{ end }}
+
+<script>
+(function () {
+  // clicking a header sorts the tbody groups of its table, numbers numerically
+  document.querySelectorAll("table.sortable-table").forEach(function (table) {
+    table.querySelectorAll("thead th.sortable").forEach(function (th) {
+      var col = th.cellIndex; // not the index among sortable headers, some columns are not
+      var asc = true;
+      th.addEventListener("click", function () {
+        var groups = Array.prototype.slice.call(table.tBodies);
+        groups.sort(function (a, b) {
+          var x = a.rows[0].cells[col].textContent.trim();
+          var y = b.rows[0].cells[col].textContent.trim();
+          var nx = parseFloat(x), ny = parseFloat(y);
+          var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+          return asc ? cmp : -cmp;
+        });
+        asc = !asc;
+        groups.forEach(function (g) { table.appendChild(g); });
+      });
+    });
+  });
+
+  document.querySelectorAll("tbody.case > tr.row").forEach(function (row) {
+    row.addEventListener("click", function () { row.parentNode.classList.toggle("open"); });
+  });
+
+  var filter = document.getElementById("filter");
+  var outcome = document.getElementById("outcome");
+  function apply() {
+    var text = filter.value.toLowerCase();
+    document.querySelectorAll("#cases tbody.case").forEach(function (group) {
+      var match = (!outcome.value || group.dataset.outcome === outcome.value) &&
+        (!text || group.rows[0].textContent.toLowerCase().indexOf(text) >= 0);
+      group.style.display = match ? "" : "none";
+    });
+  }
+  filter.addEventListener("input", apply);
+  outcome.addEventListener("change", apply);
+})();
+</script>
+</body>
+</html>
@@ -1,7 +1,17 @@
 package toolkit
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -11,70 +21,227 @@ func ReportMetrics(repoID string, targetBranch string, report UnittestReport) (R
 
 	passed := report.Summary.Passed
 	failed := report.Summary.Failed
+	flaky := report.Summary.Flaky
+	errored := report.Summary.Errored
+	skipped := report.Summary.Skipped
 
+	// skipped cases never ran, so they do not count against the pass rate;
+	// flaky cases passed in the end and count as passed
 	var passRate float32 = 0.0
-	if totalTests > 0 {
-		passRate = (float32(passed) / float32(totalTests)) * 100.0
+	if executed := totalTests - skipped; executed > 0 {
+		passRate = (float32(passed+flaky) / float32(executed)) * 100.0
 	}
 
-	uniqueEndpoints := make(map[string]int)
+	uniqueEndpoints := make(map[string]bool)
 	methodCounts := map[string]int{
 		"GET":    0,
 		"POST":   0,
 		"PUT":    0,
 		"DELETE": 0,
 	}
-	latencyArray := make([]int, 0, totalTests)
+	failureTypes := make(map[string]int)
+	latencies := make([]int64, 0, totalTests)
 
 	for _, result := range report.Results {
-		// endpoint name check
-		endpointName := result.Endpoint
-		if _, ok := uniqueEndpoints[endpointName]; !ok { // case not found in map
-			uniqueEndpoints[endpointName] = 0
-		} else {
-			uniqueEndpoints[endpointName]++
-		}
+		uniqueEndpoints[result.Endpoint] = true
 		// method counter
-		testMethod := result.Method
-		if _, ok := methodCounts[testMethod]; ok {
-			methodCounts[testMethod]++
+		if _, ok := methodCounts[result.Method]; ok {
+			methodCounts[result.Method]++
+		}
+		if (result.Outcome == OutcomeFailed || result.Outcome == OutcomeErrored) && result.Failure != "" {
+			failureTypes[result.Failure]++
+		}
+		// latency, only for cases that reached the target
+		if reachedTarget(result) {
+			latencies = append(latencies, result.LatencyMS)
 		}
-		// latency
-		testLatency := result.LatencyMS
-		latencyArray = append(latencyArray, int(testLatency))
 	}
 
-	keys := make([]string, 0, len(uniqueEndpoints))
-	for k := range uniqueEndpoints {
-		keys = append(keys, k)
+	sort.Slice(latencies, func(a, b int) bool { return latencies[a] < latencies[b] })
+	var sum int64
+	for _, lat := range latencies {
+		sum += lat
 	}
-
-	sum := 0
-	for _, lat := range latencyArray {
-		sum = sum + lat
+	var avgLatency float32 = 0.0
+	var maxLatency int64
+	if len(latencies) > 0 {
+		avgLatency = float32(sum) / float32(len(latencies))
+		maxLatency = latencies[len(latencies)-1]
+	}
+	if len(failureTypes) == 0 {
+		failureTypes = nil
 	}
 
-	var avgLatency float32 = 0.0
-	if len(latencyArray) > 0 {
-		avgLatency = float32(sum) / float32(len(latencyArray))
+	// the run id doubles as the metric id, so case results can refer to it
+	id := report.RunID
+	if id == "" {
+		id = uuid.NewString()
 	}
 
 	metrics := ReportMetric{
-		ID:                   uuid.NewString(),
+		ID:                   id,
 		RepoID:               repoID,
-		TargetBranch:		  targetBranch,
+		TargetBranch:         targetBranch,
 		TotalTests:           totalTests,
 		Passed:               passed,
 		Failed:               failed,
+		Flaky:                flaky,
+		Errored:              errored,
+		Skipped:              skipped,
 		SuccessRate:          passRate,
+		DurationMS:           report.DurationMS,
+		Commit:               report.Commit,
+		SpecHash:             report.SpecHash,
 		GetCounts:            methodCounts["GET"],
 		PostCounts:           methodCounts["POST"],
 		PutCounts:            methodCounts["PUT"],
 		DeleteCounts:         methodCounts["DELETE"],
-		CreatedAt: 			  time.Now().UTC(),
-		UniqueEndpointsCount: len(keys),
+		CreatedAt:            time.Now().UTC(),
+		UniqueEndpointsCount: len(uniqueEndpoints),
 		AverageLatency:       avgLatency,
+		LatencyP50MS:         Percentile(latencies, 50),
+		LatencyP90MS:         Percentile(latencies, 90),
+		LatencyP95MS:         Percentile(latencies, 95),
+		LatencyP99MS:         Percentile(latencies, 99),
+		LatencyMaxMS:         maxLatency,
+		Endpoints:            metricBreakdown(report.Results, true),
+		Methods:              metricBreakdown(report.Results, false),
+		FailureTypes:         failureTypes,
+		Filtered:             report.Filtered,
 	}
 
 	return metrics, nil
 }
+
+// reachedTarget is false for cases whose latency says nothing about the API:
+// skipped ones never ran and errored ones failed before sending.
+func reachedTarget(r UnittestCaseResult) bool {
+	return r.Outcome != OutcomeSkipped && r.Outcome != OutcomeErrored
+}
+
+// metricBreakdown counts outcomes per endpoint and method in report order, or
+// per method sorted by name when byEndpoint is false.
+func metricBreakdown(results []UnittestCaseResult, byEndpoint bool) []MetricBreakdown {
+	var out []MetricBreakdown
+	index := make(map[string]int)
+	latencies := make(map[int][]int64)
+	for _, r := range results {
+		key := r.Method
+		if byEndpoint {
+			key += " " + r.Endpoint
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			b := MetricBreakdown{Method: r.Method}
+			if byEndpoint {
+				b.Endpoint = r.Endpoint
+			}
+			out = append(out, b)
+		}
+		b := &out[i]
+		b.Cases++
+		switch r.Outcome {
+		case OutcomePassed:
+			b.Passed++
+		case OutcomeFlaky:
+			b.Flaky++
+		case OutcomeErrored:
+			b.Errored++
+		case OutcomeSkipped:
+			b.Skipped++
+		default:
+			b.Failed++
+		}
+		if reachedTarget(r) {
+			latencies[i] = append(latencies[i], r.LatencyMS)
+		}
+	}
+
+	for i, lat := range latencies {
+		sort.Slice(lat, func(a, b int) bool { return lat[a] < lat[b] })
+		out[i].LatencyP50MS = Percentile(lat, 50)
+		out[i].LatencyP95MS = Percentile(lat, 95)
+		out[i].LatencyMaxMS = lat[len(lat)-1]
+	}
+	if !byEndpoint {
+		sort.Slice(out, func(a, b int) bool { return out[a].Method < out[b].Method })
+	}
+	return out
+}
+
+// SpecHash identifies a spec independently of where it came from, so runs of
+// the same spec can be compared. encoding/json sorts map keys, which keeps the
+// hash stable.
+func SpecHash(spec TestSpec) string {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteFileAtomic creates the parent directories of path, lets write fill a
+// temp file next to it and renames the temp file over path, so readers never
+// see a half-written file.
+func WriteFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("prepare output directory for %q: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file for %q: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close %q: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod %q: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename into %q: %w", path, err)
+	}
+	return nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted values.
+func Percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
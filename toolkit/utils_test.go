@@ -0,0 +1,132 @@
+package toolkit
+
+import (
+	"maps"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		sorted []int64
+		p      float64
+		want   int64
+	}{
+		{nil, 50, 0},
+		{[]int64{7}, 99, 7},
+		{values, 0, 10},
+		{values, 50, 50},
+		{values, 90, 90},
+		{values, 95, 100},
+		{values, 100, 100},
+		{[]int64{1, 2, 3}, 50, 2},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v, %v) = %d, want %d", tt.sorted, tt.p, got, tt.want)
+		}
+	}
+}
+
+// metricResults covers every outcome; the errored and skipped latencies are
+// large so they show up if they leak into the percentiles.
+func metricResults() []UnittestCaseResult {
+	return []UnittestCaseResult{
+		{Endpoint: "/items", Method: "GET", Outcome: OutcomePassed, LatencyMS: 30},
+		{Endpoint: "/items", Method: "POST", Outcome: OutcomeFailed, Failure: "status_mismatch", LatencyMS: 50},
+		{Endpoint: "/items", Method: "GET", Outcome: OutcomeFlaky, LatencyMS: 10},
+		{Endpoint: "/items", Method: "GET", Outcome: OutcomeErrored, Failure: "auth_error", LatencyMS: 9000},
+		{Endpoint: "/users", Method: "GET", Outcome: OutcomeSkipped, LatencyMS: 8000},
+		{Endpoint: "/users", Method: "GET", Outcome: OutcomePassed, LatencyMS: 20},
+		{Endpoint: "/users", Method: "DELETE", Outcome: OutcomeFailed, Failure: "timeout", LatencyMS: 100},
+		{Endpoint: "/items", Method: "GET", Outcome: OutcomePassed, Failure: "ignored", LatencyMS: 40},
+	}
+}
+
+func TestMetricBreakdown(t *testing.T) {
+	results := metricResults()
+	byEndpoint := []MetricBreakdown{
+		{Endpoint: "/items", Method: "GET", Cases: 4, Passed: 2, Flaky: 1, Errored: 1, LatencyP50MS: 30, LatencyP95MS: 40, LatencyMaxMS: 40},
+		{Endpoint: "/items", Method: "POST", Cases: 1, Failed: 1, LatencyP50MS: 50, LatencyP95MS: 50, LatencyMaxMS: 50},
+		{Endpoint: "/users", Method: "GET", Cases: 2, Passed: 1, Skipped: 1, LatencyP50MS: 20, LatencyP95MS: 20, LatencyMaxMS: 20},
+		{Endpoint: "/users", Method: "DELETE", Cases: 1, Failed: 1, LatencyP50MS: 100, LatencyP95MS: 100, LatencyMaxMS: 100},
+	}
+	if got := metricBreakdown(results, true); !reflect.DeepEqual(got, byEndpoint) {
+		t.Errorf("by endpoint:\n got %+v\nwant %+v", got, byEndpoint)
+	}
+	byMethod := []MetricBreakdown{
+		{Method: "DELETE", Cases: 1, Failed: 1, LatencyP50MS: 100, LatencyP95MS: 100, LatencyMaxMS: 100},
+		{Method: "GET", Cases: 6, Passed: 3, Flaky: 1, Errored: 1, Skipped: 1, LatencyP50MS: 20, LatencyP95MS: 40, LatencyMaxMS: 40},
+		{Method: "POST", Cases: 1, Failed: 1, LatencyP50MS: 50, LatencyP95MS: 50, LatencyMaxMS: 50},
+	}
+	if got := metricBreakdown(results, false); !reflect.DeepEqual(got, byMethod) {
+		t.Errorf("by method:\n got %+v\nwant %+v", got, byMethod)
+	}
+
+	// a group with no case that reached the target has no latency
+	only := []UnittestCaseResult{{Endpoint: "/x", Method: "GET", Outcome: OutcomeSkipped, LatencyMS: 5}}
+	if got := metricBreakdown(only, true); got[0].LatencyMaxMS != 0 || got[0].LatencyP50MS != 0 {
+		t.Errorf("skipped latency counted: %+v", got[0])
+	}
+	if got := metricBreakdown(nil, true); got != nil {
+		t.Errorf("empty results: %+v", got)
+	}
+}
+
+func TestReportMetrics(t *testing.T) {
+	tests := []struct {
+		name        string
+		summary     UnittestSummary
+		successRate float32
+	}{
+		{"skipped excluded", UnittestSummary{Total: 8, Passed: 3, Failed: 2, Flaky: 1, Errored: 1, Skipped: 1}, 4.0 / 7 * 100},
+		{"flaky counts as passed", UnittestSummary{Total: 2, Passed: 1, Flaky: 1}, 100},
+		{"errored counts against", UnittestSummary{Total: 2, Passed: 1, Errored: 1}, 50},
+		{"all skipped", UnittestSummary{Total: 3, Skipped: 3}, 0},
+		{"empty", UnittestSummary{}, 0},
+	}
+	for _, tt := range tests {
+		m, err := ReportMetrics("repo", "main", UnittestReport{Summary: tt.summary})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(m.SuccessRate-tt.successRate)) > 1e-4 {
+			t.Errorf("%s: success rate %v, want %v", tt.name, m.SuccessRate, tt.successRate)
+		}
+	}
+
+	report := UnittestReport{
+		RunID:    "run-1",
+		Results:  metricResults(),
+		Summary:  UnittestSummary{Total: 8, Passed: 3, Failed: 2, Flaky: 1, Errored: 1, Skipped: 1},
+		Filtered: true,
+	}
+	m, err := ReportMetrics("repo", "main", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "run-1" || m.RepoID != "repo" || m.TargetBranch != "main" || !m.Filtered {
+		t.Errorf("identity %q %q %q filtered %v", m.ID, m.RepoID, m.TargetBranch, m.Filtered)
+	}
+	if m.GetCounts != 6 || m.PostCounts != 1 || m.DeleteCounts != 1 || m.PutCounts != 0 || m.UniqueEndpointsCount != 2 {
+		t.Errorf("counts get=%d post=%d put=%d delete=%d endpoints=%d", m.GetCounts, m.PostCounts, m.PutCounts, m.DeleteCounts, m.UniqueEndpointsCount)
+	}
+	// latencies of the six cases that reached the target: 10 20 30 40 50 100
+	latency := [...]int64{m.LatencyP50MS, m.LatencyP90MS, m.LatencyP95MS, m.LatencyP99MS, m.LatencyMaxMS}
+	if latency != [...]int64{30, 100, 100, 100, 100} || m.AverageLatency != 250.0/6 {
+		t.Errorf("latency p50/p90/p95/p99/max %v average %v", latency, m.AverageLatency)
+	}
+	wantFailures := map[string]int{"status_mismatch": 1, "auth_error": 1, "timeout": 1}
+	if !maps.Equal(m.FailureTypes, wantFailures) {
+		t.Errorf("failure types %v, want %v", m.FailureTypes, wantFailures)
+	}
+	if len(m.Endpoints) != 4 || len(m.Methods) != 3 {
+		t.Errorf("%d endpoint and %d method breakdowns", len(m.Endpoints), len(m.Methods))
+	}
+
+	if m, _ := ReportMetrics("repo", "main", UnittestReport{}); m.ID == "" || m.FailureTypes != nil {
+		t.Errorf("empty report: id %q failure types %v", m.ID, m.FailureTypes)
+	}
+}